@@ -1,6 +1,7 @@
 package debugger
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 )
@@ -8,6 +9,32 @@ import (
 type Debugger struct {
 	enabled bool
 	text    strings.Builder
+	entries []Entry
+}
+
+// Entry is a value collected by debugger with the label it was debugged with
+type Entry struct {
+	Label string
+	Value any
+}
+
+// MarshalJSON encodes label and value, value is encoded as is if it can be encoded into JSON, otherwise its text
+// representation is used
+func (e Entry) MarshalJSON() ([]byte, error) {
+	value := e.Value
+	if stringer, ok := value.(fmt.Stringer); ok {
+		if _, ok = value.(json.Marshaler); !ok {
+			value = stringer.String()
+		}
+	}
+
+	return json.Marshal(struct {
+		Label string `json:"label"`
+		Value any    `json:"value"`
+	}{
+		Label: e.Label,
+		Value: value,
+	})
 }
 
 func (d *Debugger) SetEnabled(enabled bool) {
@@ -18,12 +45,30 @@ func (d *Debugger) Enabled() bool {
 	return d.enabled
 }
 
+// Debug collects values, the first argument is a label of the rest
 func (d *Debugger) Debug(args ...any) {
 	_, _ = d.text.WriteString(fmt.Sprint(args...) + "\n")
+	if len(args) == 0 {
+		return
+	}
+
+	entry := Entry{Label: strings.TrimSpace(fmt.Sprint(args[0]))}
+	if len(args) == 2 {
+		entry.Value = args[1]
+	} else {
+		entry.Value = fmt.Sprint(args[1:]...)
+	}
+	d.entries = append(d.entries, entry)
 }
 
 func (d *Debugger) Clean() {
 	d.text.Reset()
+	d.entries = nil
+}
+
+// Entries returns values collected since the last clean
+func (d *Debugger) Entries() []Entry {
+	return d.entries
 }
 
 func (d *Debugger) String() string {
@@ -0,0 +1,185 @@
+package executor
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// AngleMode is a unit of angles that are plain numbers, it is used by trigonometric functions and their inverses,
+// angles with units, for example: `30 deg`, are not affected by it
+type AngleMode int
+
+const (
+	AngleRadians  AngleMode = iota // Full turn is 2*Pi
+	AngleDegrees                   // Full turn is 360
+	AngleGradians                  // Full turn is 400
+)
+
+var angleModeNames = map[AngleMode]string{
+	AngleRadians:  "rad",
+	AngleDegrees:  "deg",
+	AngleGradians: "grad",
+}
+
+// ParseAngleMode parses angle mode, one of `rad`, `deg` or `grad`, empty string is radians
+func ParseAngleMode(text string) (AngleMode, error) {
+	if text == "" {
+		return AngleRadians, nil
+	}
+
+	for mode, name := range angleModeNames {
+		if name == text {
+			return mode, nil
+		}
+	}
+	return AngleRadians, fmt.Errorf("invalid angle mode `%s`, expected `rad`, `deg` or `grad`", text)
+}
+
+func (m AngleMode) String() string {
+	return angleModeNames[m]
+}
+
+func (m AngleMode) validate() error {
+	if _, ok := angleModeNames[m]; !ok {
+		return fmt.Errorf("unknown angle mode %d", m)
+	}
+	return nil
+}
+
+// turn returns value of the full turn in units of the mode
+func (m AngleMode) turn(precision int32) decimal.Decimal {
+	switch m {
+	case AngleDegrees:
+		return decimal.NewFromInt(360)
+	case AngleGradians:
+		return decimal.NewFromInt(400)
+	default:
+		return fullTurn(precision)
+	}
+}
+
+// fullTurn returns the full turn in radians with the precision
+func fullTurn(precision int32) decimal.Decimal {
+	return pi(precision).Mul(decimal.NewFromInt(2))
+}
+
+// degreeSign is the only unit that is not a word
+const degreeSign = "°"
+
+// angleTurns are values of the full turn in angle units that are not radians
+var angleTurns = map[string]decimal.Decimal{
+	"deg":      decimal.NewFromInt(360),
+	degreeSign: decimal.NewFromInt(360),
+	"grad":     decimal.NewFromInt(400),
+}
+
+// angle returns value of the angle and value of the full turn in the same units, numbers are angles in the angle mode
+// and quantities are angles in their units
+func angle(s *scope, v Value) (value, turn decimal.Decimal, err error) {
+	series := seriesPrecision(s.precision)
+	quantity, ok := v.(Quantity)
+	if !ok {
+		value, err = toDecimal(v, s.precision)
+		return value, s.angle.turn(series), err
+	}
+
+	unit := quantity.unit
+	if unit.dimension() != dimAngle {
+		return decimal.Zero, decimal.Zero, fmt.Errorf("expected angle, but got %s", unit.dimension())
+	}
+	if turn, ok = angleTurns[unit.String()]; ok {
+		return quantity.value, turn, nil
+	}
+
+	radian, _ := lookupUnit("rad")
+	value, err = convert(quantity, radian, series)
+	return value, fullTurn(series), err
+}
+
+// sinCosAngle returns sine and cosine of the angle with the precision, angles that are multiples of quarter turn have
+// exact values unless they are in radians, the full turn in radians is the only one that is not an integer
+func sinCosAngle(value, turn decimal.Decimal, precision int32) (decimal.Decimal, decimal.Decimal) {
+	if !turn.IsInteger() {
+		return sinCos(value, precision)
+	}
+
+	value = value.Mod(turn)
+	quarter := turn.Div(decimal.NewFromInt(4))
+	if value.Mod(quarter).IsZero() {
+		quarters := value.Div(quarter).IntPart()
+		if quarters < 0 {
+			quarters += 4
+		}
+		sin := [4]int64{0, 1, 0, -1}
+		cos := [4]int64{1, 0, -1, 0}
+		return decimal.NewFromInt(sin[quarters]), decimal.NewFromInt(cos[quarters])
+	}
+
+	series := seriesPrecision(precision)
+	return sinCos(value.Mul(fullTurn(series)).DivRound(turn, series), precision)
+}
+
+// fromRadians converts angle in radians into the angle mode
+func fromRadians(s *scope, radians decimal.Decimal) Value {
+	if s.angle == AngleRadians {
+		return NewNumber(radians)
+	}
+
+	series := seriesPrecision(s.precision)
+	return NewNumber(radians.Mul(s.angle.turn(series)).DivRound(fullTurn(series), s.precision))
+}
+
+func sine(s *scope, args []Value) (Value, error) {
+	value, turn, err := angle(s, args[0])
+	if err != nil {
+		return nil, err
+	}
+	sin, _ := sinCosAngle(value, turn, s.precision)
+	return NewNumber(sin), nil
+}
+
+func cosine(s *scope, args []Value) (Value, error) {
+	value, turn, err := angle(s, args[0])
+	if err != nil {
+		return nil, err
+	}
+	_, cos := sinCosAngle(value, turn, s.precision)
+	return NewNumber(cos), nil
+}
+
+func tangent(s *scope, args []Value) (Value, error) {
+	value, turn, err := angle(s, args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	sin, cos := sinCosAngle(value, turn, seriesPrecision(s.precision))
+	if cos.IsZero() {
+		return nil, fmt.Errorf("infinity")
+	}
+	return NewNumber(sin.DivRound(cos, s.precision)), nil
+}
+
+// applyInverseTrig applies inverse trigonometric function that returns angle in radians, the result is converted into
+// the angle mode
+func applyInverseTrig(
+	apply func(args []decimal.Decimal, precision int32) (decimal.Decimal, error),
+) func(s *scope, args []Value) (Value, error) {
+	return func(s *scope, args []Value) (Value, error) {
+		values := make([]decimal.Decimal, len(args))
+		for i, arg := range args {
+			var err error
+			values[i], err = toDecimal(arg, s.precision)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		radians, err := apply(values, s.precision)
+		if err != nil {
+			return nil, err
+		}
+		return fromRadians(s, radians), nil
+	}
+}
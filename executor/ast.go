@@ -0,0 +1,135 @@
+package executor
+
+import (
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// node is a node of abstract syntax tree
+type node interface {
+	location() Location
+	String() string
+}
+
+type numberNode struct {
+	loc   Location
+	value decimal.Decimal
+}
+
+func (n *numberNode) location() Location {
+	return n.loc
+}
+
+func (n *numberNode) String() string {
+	return n.value.String()
+}
+
+type identifierNode struct {
+	loc        Location
+	identifier *Identifier
+}
+
+func (n *identifierNode) location() Location {
+	return n.loc
+}
+
+func (n *identifierNode) String() string {
+	return n.identifier.text
+}
+
+type unaryNode struct {
+	loc      Location
+	opLoc    Location
+	operator *Operator
+	operand  node
+}
+
+func (n *unaryNode) location() Location {
+	return n.loc
+}
+
+func (n *unaryNode) String() string {
+	if n.operator.postfix {
+		return "(" + n.operand.String() + n.operator.text + ")"
+	}
+	return "(" + n.operator.text + n.operand.String() + ")"
+}
+
+type binaryNode struct {
+	loc      Location
+	opLoc    Location
+	operator *Operator
+	left     node
+	right    node
+}
+
+func (n *binaryNode) location() Location {
+	return n.loc
+}
+
+func (n *binaryNode) String() string {
+	return "(" + n.left.String() + " " + n.operator.text + " " + n.right.String() + ")"
+}
+
+type callNode struct {
+	loc        Location
+	nameLoc    Location
+	identifier *Identifier
+	args       []node
+}
+
+func (n *callNode) location() Location {
+	return n.loc
+}
+
+func (n *callNode) String() string {
+	args := make([]string, len(n.args))
+	for i, arg := range n.args {
+		args[i] = arg.String()
+	}
+	return n.identifier.text + "(" + strings.Join(args, ", ") + ")"
+}
+
+type listNode struct {
+	loc      Location
+	elements []node
+}
+
+func (n *listNode) location() Location {
+	return n.loc
+}
+
+func (n *listNode) String() string {
+	elements := make([]string, len(n.elements))
+	for i, element := range n.elements {
+		elements[i] = element.String()
+	}
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+type indexNode struct {
+	loc      Location
+	indexLoc Location // Location of the index with brackets
+	list     node
+	index    node
+}
+
+func (n *indexNode) location() Location {
+	return n.loc
+}
+
+func (n *indexNode) String() string {
+	return n.list.String() + "[" + n.index.String() + "]"
+}
+
+// span returns location that covers both locations
+func span(from, to Location) Location {
+	return Location{
+		Start:       from.Start,
+		End:         to.End,
+		Line:        from.Line,
+		StartColumn: from.StartColumn,
+		EndColumn:   to.EndColumn,
+	}
+}
@@ -0,0 +1,414 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// Complex is a complex number with non-zero imaginary part, complex results with zero imaginary part are numbers
+type Complex struct {
+	re decimal.Decimal
+	im decimal.Decimal
+}
+
+// NewComplex creates new complex number, if imaginary part is zero number is returned instead
+func NewComplex(re, im decimal.Decimal) Value {
+	if im.IsZero() {
+		return NewNumber(re)
+	}
+	return Complex{re: re, im: im}
+}
+
+// Real returns real part of the complex number
+func (c Complex) Real() decimal.Decimal {
+	return c.re
+}
+
+// Imag returns imaginary part of the complex number
+func (c Complex) Imag() decimal.Decimal {
+	return c.im
+}
+
+func (c Complex) String() string {
+	return formatComplex(c, decimal.Decimal.String)
+}
+
+func (c Complex) isValue() {}
+
+var imaginaryUnit = Complex{re: decimal.Zero, im: decimal.NewFromInt(1)}
+
+// imaginaryIdentifier is the imaginary unit `i`, it is resolved after user variables and units
+var imaginaryIdentifier = Identifier{
+	text:     "i",
+	name:     "imaginary unit",
+	variable: true,
+	apply:    applyConstantIdent(imaginaryUnit),
+}
+
+// isComplex reports whether any of values is complex
+func isComplex(values ...Value) bool {
+	for _, v := range values {
+		if _, ok := v.(Complex); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// toComplex converts number or rational into complex number with zero imaginary part
+func toComplex(v Value, precision int32) (Complex, error) {
+	switch v := v.(type) {
+	case Complex:
+		return v, nil
+	case Number, Rational:
+		re, _ := toDecimal(v, precision)
+		return Complex{re: re}, nil
+	default:
+		return Complex{}, fmt.Errorf("expected number, but got `%s`", v)
+	}
+}
+
+func applyComplexOp(
+	v1, v2 Value, precision int32, apply func(c1, c2 Complex) (Value, error),
+) (Value, error) {
+	c1, err := toComplex(v1, precision)
+	if err != nil {
+		return nil, err
+	}
+	c2, err := toComplex(v2, precision)
+	if err != nil {
+		return nil, err
+	}
+	return apply(c1, c2)
+}
+
+func (c Complex) add(other Complex) (Value, error) {
+	return NewComplex(c.re.Add(other.re), c.im.Add(other.im)), nil
+}
+
+func (c Complex) sub(other Complex) (Value, error) {
+	return NewComplex(c.re.Sub(other.re), c.im.Sub(other.im)), nil
+}
+
+func (c Complex) mul(other Complex) (Value, error) {
+	return NewComplex(
+		c.re.Mul(other.re).Sub(c.im.Mul(other.im)),
+		c.re.Mul(other.im).Add(c.im.Mul(other.re)),
+	), nil
+}
+
+func (c Complex) div(other Complex, precision int32) (Value, error) {
+	denominator := other.re.Mul(other.re).Add(other.im.Mul(other.im))
+	if denominator.IsZero() {
+		return nil, fmt.Errorf("division by zero")
+	}
+
+	return NewComplex(
+		c.re.Mul(other.re).Add(c.im.Mul(other.im)).DivRound(denominator, precision),
+		c.im.Mul(other.re).Sub(c.re.Mul(other.im)).DivRound(denominator, precision),
+	), nil
+}
+
+func (c Complex) isZero() bool {
+	return c.re.IsZero() && c.im.IsZero()
+}
+
+// pow raises complex number to the power, integer powers are calculated by multiplication to keep results exact
+func (c Complex) pow(exponent Complex, precision int32) (Value, error) {
+	if exponent.im.IsZero() && exponent.re.IsInteger() && exponent.re.Abs().LessThanOrEqual(
+		decimal.NewFromInt(maxExactExponent),
+	) {
+		return c.powInt(exponent.re.IntPart(), precision)
+	}
+
+	if c.isZero() {
+		if exponent.re.IsPositive() {
+			return NewNumber(decimal.Zero), nil
+		}
+		return nil, fmt.Errorf("undefined value (0 ^ %s)", exponent)
+	}
+
+	// z^w = exp(w * ln(z))
+	ln, err := c.ln(seriesPrecision(precision))
+	if err != nil {
+		return nil, err
+	}
+	t, _ := exponent.mul(ln)
+	power, _ := toComplex(t, precision)
+	return power.exp(precision)
+}
+
+func (c Complex) powInt(n int64, precision int32) (Value, error) {
+	switch {
+	case c.isZero() && n == 0:
+		return nil, fmt.Errorf("undefined value (0 ^ 0)")
+	case c.isZero() && n < 0:
+		return nil, fmt.Errorf("infinity")
+	}
+
+	base := c
+	if n < 0 {
+		inverse, err := Complex{re: decimal.NewFromInt(1)}.div(c, precision)
+		if err != nil {
+			return nil, err
+		}
+		base, _ = toComplex(inverse, precision)
+		n = -n
+	}
+
+	result := Complex{re: decimal.NewFromInt(1)}
+	for n > 0 {
+		if n%2 == 1 {
+			product, _ := result.mul(base)
+			result, _ = toComplex(product, precision)
+		}
+		square, _ := base.mul(base)
+		base, _ = toComplex(square, precision)
+		n /= 2
+	}
+
+	return NewComplex(result.re, result.im), nil
+}
+
+// ln returns principal value of natural logarithm
+func (c Complex) ln(precision int32) (Complex, error) {
+	abs, err := c.abs(seriesPrecision(precision))
+	if err != nil {
+		return Complex{}, err
+	}
+
+	re, err := naturalLog(abs, precision)
+	if err != nil {
+		return Complex{}, err
+	}
+	return Complex{re: re, im: c.arg(precision)}, nil
+}
+
+func (c Complex) exp(precision int32) (Value, error) {
+	magnitude, err := exponential(c.re, seriesPrecision(precision))
+	if err != nil {
+		return nil, err
+	}
+
+	// Digits before the point of the magnitude are multiplied by sine and cosine
+	sin, cos := sinCos(c.im, seriesPrecision(precision)+integerDigits(magnitude))
+	return NewComplex(magnitude.Mul(cos).Round(precision), magnitude.Mul(sin).Round(precision)), nil
+}
+
+// sqrt returns principal square root
+func (c Complex) sqrt(precision int32) (Value, error) {
+	abs, err := c.abs(seriesPrecision(precision))
+	if err != nil {
+		return nil, err
+	}
+
+	half := decimal.NewFromFloat(0.5)
+	re, err := squareRoot(abs.Add(c.re).Mul(half), precision)
+	if err != nil {
+		return nil, err
+	}
+	im, err := squareRoot(abs.Sub(c.re).Mul(half), precision)
+	if err != nil {
+		return nil, err
+	}
+	if c.im.IsNegative() {
+		im = im.Neg()
+	}
+
+	return NewComplex(re, im), nil
+}
+
+func (c Complex) abs(precision int32) (decimal.Decimal, error) {
+	return squareRoot(c.re.Mul(c.re).Add(c.im.Mul(c.im)), precision)
+}
+
+// arg returns argument in range (-Pi, Pi]
+func (c Complex) arg(precision int32) decimal.Decimal {
+	return atan2(c.im, c.re, precision)
+}
+
+func (c Complex) conj() Value {
+	return NewComplex(c.re, c.im.Neg())
+}
+
+func (c Complex) equal(other Complex) bool {
+	return c.re.Equal(other.re) && c.im.Equal(other.im)
+}
+
+// squareRoot returns square root truncated to the precision, it is calculated as integer square root of the value
+// scaled by `10^(2*precision)`
+func squareRoot(v decimal.Decimal, precision int32) (decimal.Decimal, error) {
+	if v.IsNegative() {
+		return decimal.Zero, fmt.Errorf("square root of negative number")
+	}
+
+	scaled := v.Shift(2 * precision).BigInt()
+	return decimal.NewFromBigInt(scaled.Sqrt(scaled), -precision), nil
+}
+
+func atan2(y, x decimal.Decimal, precision int32) decimal.Decimal {
+	series := seriesPrecision(precision)
+	halfPi := pi(series).DivRound(decimal.NewFromInt(2), precision)
+	switch {
+	case x.IsPositive():
+		return atan(y.DivRound(x, series), precision)
+	case x.IsNegative() && y.IsNegative():
+		return atan(y.DivRound(x, series), series).Sub(pi(series)).Round(precision)
+	case x.IsNegative():
+		return atan(y.DivRound(x, series), series).Add(pi(series)).Round(precision)
+	case y.IsPositive():
+		return halfPi
+	case y.IsNegative():
+		return halfPi.Neg()
+	default:
+		return decimal.Zero
+	}
+}
+
+// seriesPrecision returns precision of series terms, it is higher than precision of the result to not accumulate
+// errors
+func seriesPrecision(precision int32) int32 {
+	return precision + 4
+}
+
+// sinCos returns sine and cosine calculated by Taylor series with the precision
+func sinCos(x decimal.Decimal, precision int32) (decimal.Decimal, decimal.Decimal) {
+	series := seriesPrecision(precision)
+	twoPi := pi(series + integerDigits(x)).Mul(decimal.NewFromInt(2))
+	x = x.Sub(twoPi.Mul(x.DivRound(twoPi, 0))).Round(series) // Reduce to [-Pi, Pi]
+
+	epsilon := decimal.New(1, -series)
+	square := x.Mul(x).Round(series)
+	sin, cos := decimal.Zero, decimal.Zero
+	sinTerm, cosTerm := x, decimal.NewFromInt(1)
+	for n := int64(1); sinTerm.Abs().GreaterThan(epsilon) || cosTerm.Abs().GreaterThan(epsilon); n += 2 {
+		sin = sin.Add(sinTerm)
+		cos = cos.Add(cosTerm)
+		sinTerm = sinTerm.Mul(square).DivRound(decimal.NewFromInt(-(n+1)*(n+2)), series)
+		cosTerm = cosTerm.Mul(square).DivRound(decimal.NewFromInt(-n*(n+1)), series)
+	}
+
+	return sin.Round(precision), cos.Round(precision)
+}
+
+// atan returns arctangent calculated by Taylor series with the precision
+func atan(x decimal.Decimal, precision int32) decimal.Decimal {
+	series := seriesPrecision(precision)
+	one := decimal.NewFromInt(1)
+	if x.Abs().GreaterThan(one) {
+		halfPi := pi(series).DivRound(decimal.NewFromInt(2), series)
+		if x.IsNegative() {
+			halfPi = halfPi.Neg()
+		}
+		return halfPi.Sub(atan(one.DivRound(x, series), series)).Round(precision)
+	}
+
+	// Reduce argument using atan(x) = 2 * atan(x / (1 + sqrt(1 + x^2))) for series to converge faster
+	const halvings = 2
+	for range halvings {
+		root, _ := squareRoot(one.Add(x.Mul(x)), series)
+		x = x.DivRound(one.Add(root), series)
+	}
+
+	epsilon := decimal.New(1, -series)
+	square := x.Mul(x).Round(series)
+	result, power := decimal.Zero, x
+	for n := int64(1); power.Abs().GreaterThan(epsilon); n += 2 {
+		result = result.Add(power.DivRound(decimal.NewFromInt(n), series))
+		power = power.Mul(square).Neg().Round(series)
+	}
+
+	return result.Mul(decimal.NewFromInt(1 << halvings)).Round(precision)
+}
+
+// formatComplex formats complex number as `1+2i`, `-i` or `2i`, zero parts are omitted
+func formatComplex(c Complex, format func(value decimal.Decimal) string) string {
+	zero := format(decimal.Zero)
+	re, im := format(c.re), format(c.im)
+	if im == zero {
+		return re
+	}
+
+	switch im {
+	case format(decimal.NewFromInt(1)):
+		im = ""
+	case format(decimal.NewFromInt(-1)):
+		im = "-"
+	}
+	if re == zero {
+		return im + "i"
+	}
+
+	if !strings.HasPrefix(im, "-") {
+		im = "+" + im
+	}
+	return re + im + "i"
+}
+
+// powComplex is a power used in complex mode, negative numbers raised to fractional powers result in complex numbers
+func powComplex(v1, v2 Value, precision int32) (Value, error) {
+	base, err1 := toDecimal(v1, precision)
+	exponent, err2 := toDecimal(v2, precision)
+	if err1 == nil && err2 == nil && base.IsNegative() && !exponent.IsInteger() {
+		return applyComplexOp(v1, v2, precision, func(c1, c2 Complex) (Value, error) {
+			return c1.pow(c2, precision)
+		})
+	}
+	return pow(v1, v2, precision)
+}
+
+// sqrt returns principal square root, negative numbers have complex roots only in complex mode
+func sqrt(s *scope, args []Value) (Value, error) {
+	if c, ok := args[0].(Complex); ok {
+		return c.sqrt(s.precision)
+	}
+
+	value, err := toDecimal(args[0], s.precision)
+	if err != nil {
+		return nil, err
+	}
+	if s.complex && value.IsNegative() {
+		return Complex{re: value}.sqrt(s.precision)
+	}
+
+	result, err := squareRoot(value, s.precision)
+	if err != nil {
+		return nil, err
+	}
+	return NewNumber(result), nil
+}
+
+// modulus returns absolute value of number or modulus of complex number
+func modulus(s *scope, args []Value) (Value, error) {
+	return applyComplexFunc(func(c Complex, precision int32) (Value, error) {
+		abs, err := c.abs(precision)
+		if err != nil {
+			return nil, err
+		}
+		return NewNumber(abs), nil
+	}, applyExactOp(absRational, decimal.Decimal.Abs))(s, args)
+}
+
+// argument returns argument of complex or real number in the angle mode
+func argument(s *scope, args []Value) (Value, error) {
+	if c, ok := args[0].(Complex); ok {
+		return fromRadians(s, c.arg(s.precision)), nil
+	}
+	return applyInverseTrig(func(args []decimal.Decimal, precision int32) (decimal.Decimal, error) {
+		return atan2(decimal.Zero, args[0], precision), nil
+	})(s, args)
+}
+
+// applyComplexFunc applies complex function if the argument is complex and regular function otherwise
+func applyComplexFunc(
+	complex func(c Complex, precision int32) (Value, error), apply func(s *scope, args []Value) (Value, error),
+) func(s *scope, args []Value) (Value, error) {
+	return func(s *scope, args []Value) (Value, error) {
+		if c, ok := args[0].(Complex); ok {
+			return complex(c, s.precision)
+		}
+		return apply(s, args)
+	}
+}
@@ -0,0 +1,292 @@
+package executor
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/shopspring/decimal"
+)
+
+// maxExpArgument is the maximal magnitude of exponent argument, larger results have too many digits
+var maxExpArgument = decimal.NewFromInt(10_000)
+
+// naturalLog returns natural logarithm with the precision, the argument is split into `m * 10^k`, where m is in
+// range [1, 10), so that `ln(x) = ln(m) + k * ln(10)`
+func naturalLog(x decimal.Decimal, precision int32) (decimal.Decimal, error) {
+	switch {
+	case x.IsZero():
+		return decimal.Zero, fmt.Errorf("logarithm of zero")
+	case x.IsNegative():
+		return decimal.Zero, fmt.Errorf("logarithm of negative number")
+	case x.Equal(decimal.NewFromInt(1)):
+		return decimal.Zero, nil
+	}
+
+	k := int32(x.NumDigits()) + x.Exponent() - 1
+	result := reducedLog(x.Shift(-k), seriesPrecision(precision))
+	if k != 0 {
+		ten := reducedLog(decimal.NewFromInt(10), seriesPrecision(precision)+integerDigits(decimal.NewFromInt32(k)))
+		result = result.Add(ten.Mul(decimal.NewFromInt32(k)))
+	}
+	return result.Round(precision), nil
+}
+
+// logRoots is the number of square roots taken from arguments of logarithm series, error of the result is multiplied
+// by 2^logRoots that is less than 10^logRootsDigits
+const (
+	logRoots       = 32
+	logRootsDigits = 10
+)
+
+// reducedLog returns natural logarithm of x in range [1, 10], square roots bring the argument close to one where
+// series `ln(x) = 2 * (y + y^3/3 + y^5/5 + ...)`, where `y = (x - 1) / (x + 1)`, converges fast
+func reducedLog(x decimal.Decimal, precision int32) decimal.Decimal {
+	work := precision + logRootsDigits
+	for range logRoots {
+		x, _ = squareRoot(x, work)
+	}
+
+	one := decimal.NewFromInt(1)
+	y := x.Sub(one).DivRound(x.Add(one), work)
+	epsilon := decimal.New(1, -work)
+	square := y.Mul(y).Round(work)
+	result, power := decimal.Zero, y
+	for n := int64(1); power.Abs().GreaterThan(epsilon); n += 2 {
+		result = result.Add(power.DivRound(decimal.NewFromInt(n), work))
+		power = power.Mul(square).Round(work)
+	}
+
+	return result.Mul(decimal.NewFromInt(2 << logRoots)).Round(precision)
+}
+
+// logarithm returns logarithm of x to the base with the precision
+func logarithm(x, base decimal.Decimal, precision int32) (decimal.Decimal, error) {
+	if !base.IsPositive() || base.Equal(decimal.NewFromInt(1)) {
+		return decimal.Zero, fmt.Errorf("logarithm base must be positive and not equal to 1")
+	}
+
+	series := seriesPrecision(precision)
+	value, err := naturalLog(x, series)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	divisor, _ := naturalLog(base, series)
+	return value.DivRound(divisor, series).Round(precision), nil
+}
+
+// exponential returns e^x with the precision, the argument is halved until it is small, so that Taylor series
+// converges fast, and the result of the series is squared back
+func exponential(x decimal.Decimal, precision int32) (decimal.Decimal, error) {
+	if x.Abs().GreaterThan(maxExpArgument) {
+		if x.IsNegative() {
+			return decimal.Zero, nil
+		}
+		return decimal.Zero, fmt.Errorf("value is too large")
+	}
+
+	one := decimal.NewFromInt(1)
+	half := decimal.NewFromFloat(0.5)
+	threshold := decimal.New(1, -3)
+	reduced, halvings := x.Abs(), 0
+	for reduced.GreaterThan(threshold) {
+		reduced = reduced.Mul(half)
+		halvings++
+	}
+
+	// Every squaring doubles relative error and digits before the point must be correct as well, e^x has less than
+	// x/2 of them
+	workPrecision := precision + 4 + int32(halvings) + int32(x.Abs().IntPart()/2)
+	epsilon := decimal.New(1, -workPrecision)
+	result, term := one, one
+	for n := int64(1); term.GreaterThan(epsilon); n++ {
+		term = term.Mul(reduced).DivRound(decimal.NewFromInt(n), workPrecision)
+		result = result.Add(term)
+	}
+	for range halvings {
+		result = result.Mul(result).Round(workPrecision)
+	}
+
+	if x.IsNegative() {
+		return one.DivRound(result, precision), nil
+	}
+	return result.Round(precision), nil
+}
+
+// nthRoot returns real root of the degree with the precision, odd roots of negative numbers are negative
+func nthRoot(x, degree decimal.Decimal, precision int32) (decimal.Decimal, error) {
+	switch {
+	case !degree.IsInteger() || degree.IsZero():
+		return decimal.Zero, fmt.Errorf("root degree must be a non-zero integer")
+	case x.IsNegative() && degree.Mod(decimal.NewFromInt(2)).IsZero():
+		return decimal.Zero, fmt.Errorf("even root of negative number")
+	case x.IsZero() && degree.IsNegative():
+		return decimal.Zero, fmt.Errorf("infinity")
+	case x.IsZero():
+		return decimal.Zero, nil
+	}
+
+	series := seriesPrecision(precision)
+	value, err := naturalLog(x.Abs(), series)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	exponent := value.DivRound(degree, series)
+
+	// Digits before the point of large roots need more precise logarithm
+	if digits := int32(exponent.IntPart() / 2); digits > 0 {
+		value, _ = naturalLog(x.Abs(), series+digits)
+		exponent = value.DivRound(degree, series+digits)
+	}
+
+	result, err := exponential(exponent, series)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if x.IsNegative() {
+		result = result.Neg()
+	}
+	return result.Round(precision), nil
+}
+
+// arcSine returns arcsine in range [-Pi/2, Pi/2]
+func arcSine(x decimal.Decimal, precision int32) (decimal.Decimal, error) {
+	one := decimal.NewFromInt(1)
+	if x.Abs().GreaterThan(one) {
+		return decimal.Zero, fmt.Errorf("arcsine of number outside of [-1, 1]")
+	}
+	root, _ := squareRoot(one.Sub(x.Mul(x)), seriesPrecision(precision))
+	return atan2(x, root, precision), nil
+}
+
+// arcCosine returns arccosine in range [0, Pi]
+func arcCosine(x decimal.Decimal, precision int32) (decimal.Decimal, error) {
+	one := decimal.NewFromInt(1)
+	if x.Abs().GreaterThan(one) {
+		return decimal.Zero, fmt.Errorf("arccosine of number outside of [-1, 1]")
+	}
+	root, _ := squareRoot(one.Sub(x.Mul(x)), seriesPrecision(precision))
+	return atan2(root, x, precision), nil
+}
+
+// sinhCosh returns hyperbolic sine and cosine
+func sinhCosh(x decimal.Decimal, precision int32) (decimal.Decimal, decimal.Decimal, error) {
+	positive, err := exponential(x, seriesPrecision(precision))
+	if err != nil {
+		return decimal.Zero, decimal.Zero, err
+	}
+	negative, err := exponential(x.Neg(), seriesPrecision(precision))
+	if err != nil {
+		return decimal.Zero, decimal.Zero, err
+	}
+
+	half := decimal.NewFromFloat(0.5)
+	return positive.Sub(negative).Mul(half).Round(precision),
+		positive.Add(negative).Mul(half).Round(precision), nil
+}
+
+// hyperbolicTangent returns hyperbolic tangent calculated as `(1 - e^-2|x|) / (1 + e^-2|x|)` to not overflow
+func hyperbolicTangent(x decimal.Decimal, precision int32) (decimal.Decimal, error) {
+	one := decimal.NewFromInt(1)
+	value, err := exponential(x.Abs().Mul(decimal.NewFromInt(-2)), seriesPrecision(precision))
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	result := one.Sub(value).DivRound(one.Add(value), precision)
+	if x.IsNegative() {
+		result = result.Neg()
+	}
+	return result, nil
+}
+
+// inverseSinh returns inverse hyperbolic sine `ln(x + sqrt(x^2 + 1))`, calculated for absolute value of x to not lose
+// precision
+func inverseSinh(x decimal.Decimal, precision int32) (decimal.Decimal, error) {
+	root, _ := squareRoot(x.Mul(x).Add(decimal.NewFromInt(1)), seriesPrecision(precision))
+	result, err := naturalLog(x.Abs().Add(root), precision)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if x.IsNegative() {
+		result = result.Neg()
+	}
+	return result, nil
+}
+
+// inverseCosh returns inverse hyperbolic cosine `ln(x + sqrt(x^2 - 1))`
+func inverseCosh(x decimal.Decimal, precision int32) (decimal.Decimal, error) {
+	one := decimal.NewFromInt(1)
+	if x.LessThan(one) {
+		return decimal.Zero, fmt.Errorf("inverse hyperbolic cosine of number less than 1")
+	}
+	root, _ := squareRoot(x.Mul(x).Sub(one), seriesPrecision(precision))
+	return naturalLog(x.Add(root), precision)
+}
+
+// inverseTanh returns inverse hyperbolic tangent `ln((1 + x) / (1 - x)) / 2`
+func inverseTanh(x decimal.Decimal, precision int32) (decimal.Decimal, error) {
+	one := decimal.NewFromInt(1)
+	if x.Abs().GreaterThanOrEqual(one) {
+		return decimal.Zero, fmt.Errorf("inverse hyperbolic tangent of number outside of (-1, 1)")
+	}
+
+	series := seriesPrecision(precision)
+	value, err := naturalLog(one.Add(x).DivRound(one.Sub(x), series), series)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return value.Mul(decimal.NewFromFloat(0.5)).Round(precision), nil
+}
+
+// hypot returns length of hypotenuse, values must have the same dimension
+func hypot(v1, v2 Value, precision int32) (Value, error) {
+	if isComplex(v1, v2) || isList(v1, v2) {
+		return nil, fmt.Errorf("expected real numbers, but got `%s` and `%s`", v1, v2)
+	}
+	x, y, unit, err := sameDimension(v1, v2, precision)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := squareRoot(x.Mul(x).Add(y.Mul(y)), precision)
+	if err != nil {
+		return nil, err
+	}
+	return newQuantity(result, unit, precision), nil
+}
+
+// sign returns -1, 0 or 1 depending on the sign of the value, units are dropped
+func sign(v Value) (Value, error) {
+	switch v := v.(type) {
+	case Number:
+		return count(v.value.Sign(), v), nil
+	case Rational:
+		return count(v.value.Sign(), v), nil
+	case Quantity:
+		return count(v.value.Sign(), v), nil
+	}
+
+	_, err := toDecimal(v, defaultPrecision)
+	return nil, err
+}
+
+// integerDigits returns number of digits before the point
+func integerDigits(d decimal.Decimal) int32 {
+	return max(int32(d.NumDigits())+d.Exponent(), 0)
+}
+
+func truncRational(r *big.Rat) *big.Rat {
+	return new(big.Rat).SetInt(new(big.Int).Quo(r.Num(), r.Denom()))
+}
+
+func fracRational(r *big.Rat) *big.Rat {
+	return new(big.Rat).Sub(r, truncRational(r))
+}
+
+func truncDecimal(d decimal.Decimal) decimal.Decimal {
+	return d.Truncate(0)
+}
+
+func fracDecimal(d decimal.Decimal) decimal.Decimal {
+	return d.Sub(d.Truncate(0))
+}
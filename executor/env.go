@@ -0,0 +1,420 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/mymmrac/mm/utils"
+)
+
+// Env holds host and user defined values that persist between executions
+type Env struct {
+	mu          sync.RWMutex
+	identifiers []Identifier // Defined by host
+	variables   map[string]Value
+	functions   map[string]*Function
+	columns     []string // Columns of the current input line, referenced as `$1`, `$2`, ...
+}
+
+// Function is user defined function
+type Function struct {
+	name   string
+	params []string
+	body   node
+	calls  []string
+}
+
+func NewEnv() *Env {
+	return &Env{
+		variables: make(map[string]Value),
+		functions: make(map[string]*Function),
+	}
+}
+
+// DefineConstant defines constant that can be used in expressions, but can't be redefined by the user
+func (env *Env) DefineConstant(name string, value decimal.Decimal) error {
+	return env.define(Identifier{
+		text:     name,
+		name:     "constant " + name,
+		variable: true,
+		apply:    applyConstantIdent(NewNumber(value)),
+	})
+}
+
+// DefineVariable defines variable which value is resolved by calling get at evaluation time
+func (env *Env) DefineVariable(name string, get func() (decimal.Decimal, error)) error {
+	utils.Assert(get != nil, "variable getter must not be nil")
+
+	return env.define(Identifier{
+		text:     name,
+		name:     "variable " + name,
+		variable: true,
+		apply: applyNullaryIdent(func() (Value, error) {
+			value, err := get()
+			if err != nil {
+				return nil, err
+			}
+			return NewNumber(value), nil
+		}),
+	})
+}
+
+// DefineFunction defines function with fixed number of arguments, functions with the same name, but different arity
+// can be defined, quantities with units can't be passed to host functions
+func (env *Env) DefineFunction(
+	name string, arity uint, apply func(args []decimal.Decimal) (decimal.Decimal, error),
+) error {
+	utils.Assert(apply != nil, "function must not be nil")
+
+	return env.define(Identifier{
+		text:  name,
+		name:  "function " + name,
+		arity: exactly(arity),
+		apply: applyDecimalArgs(func(args []decimal.Decimal, _ int32) (decimal.Decimal, error) {
+			return apply(args)
+		}),
+	})
+}
+
+// DefineVariadicFunction defines function that accepts minArity or more arguments
+func (env *Env) DefineVariadicFunction(
+	name string, minArity uint, apply func(args []decimal.Decimal) (decimal.Decimal, error),
+) error {
+	utils.Assert(apply != nil, "function must not be nil")
+
+	return env.define(Identifier{
+		text:  name,
+		name:  "function " + name,
+		arity: atLeast(minArity),
+		apply: applyDecimalArgs(func(args []decimal.Decimal, _ int32) (decimal.Decimal, error) {
+			return apply(args)
+		}),
+	})
+}
+
+func (env *Env) define(identifier Identifier) error {
+	if err := checkName(identifier.text); err != nil {
+		return err
+	}
+	if slices.ContainsFunc(knownIdentifiers, func(ident Identifier) bool {
+		return ident.text == identifier.text
+	}) {
+		return fmt.Errorf("can't redefine built-in identifier `%s`", identifier.text)
+	}
+
+	env.mu.Lock()
+	defer env.mu.Unlock()
+
+	for _, ident := range env.identifiers {
+		if ident.text != identifier.text {
+			continue
+		}
+		if ident.variable || identifier.variable || ident.arity.overlaps(identifier.arity) {
+			return fmt.Errorf("identifier `%s` already defined", identifier.text)
+		}
+	}
+	if _, ok := env.variables[identifier.text]; ok {
+		return fmt.Errorf("identifier `%s` already defined", identifier.text)
+	}
+
+	env.identifiers = append(env.identifiers, identifier)
+	return nil
+}
+
+func (env *Env) hostIdentifiers() []Identifier {
+	env.mu.RLock()
+	defer env.mu.RUnlock()
+
+	return slices.Clone(env.identifiers)
+}
+
+// hostIdentifier returns host defined variable or function that accepts given number of arguments
+func (env *Env) hostIdentifier(name string, variable bool, args uint) (*Identifier, bool) {
+	env.mu.RLock()
+	defer env.mu.RUnlock()
+
+	for _, ident := range env.identifiers {
+		if ident.text != name || ident.variable != variable {
+			continue
+		}
+
+		if variable || ident.arity.accepts(args) {
+			return &ident, true
+		}
+	}
+
+	return nil, false
+}
+
+// Variable returns value of user variable
+func (env *Env) Variable(name string) (Value, bool) {
+	env.mu.RLock()
+	defer env.mu.RUnlock()
+
+	value, ok := env.variables[name]
+	return value, ok
+}
+
+// SetVariable sets value of user variable, built-in and host defined identifiers or units can't be redefined
+func (env *Env) SetVariable(name string, value Value) error {
+	if err := env.checkVariable(name); err != nil {
+		return err
+	}
+
+	env.mu.Lock()
+	defer env.mu.Unlock()
+
+	env.variables[name] = value
+	return nil
+}
+
+// SetColumns sets columns of the current input line, column `$n` is a number in n-th column, columns are parsed
+// when they are referenced, so columns that aren't numbers can be present
+func (env *Env) SetColumns(columns []string) {
+	env.mu.Lock()
+	defer env.mu.Unlock()
+
+	env.columns = slices.Clone(columns)
+}
+
+// column returns text of column with the given number, columns are numbered from one
+func (env *Env) column(number int) (string, bool) {
+	env.mu.RLock()
+	defer env.mu.RUnlock()
+
+	if number > len(env.columns) {
+		return "", false
+	}
+	return env.columns[number-1], true
+}
+
+// Names returns names of all host and user defined identifiers
+func (env *Env) Names() []string {
+	env.mu.RLock()
+	defer env.mu.RUnlock()
+
+	names := make([]string, 0, len(env.identifiers)+len(env.variables)+len(env.functions))
+	for _, ident := range env.identifiers {
+		names = append(names, ident.text)
+	}
+	for name := range env.variables {
+		names = append(names, name)
+	}
+	for _, fn := range env.functions {
+		names = append(names, fn.name)
+	}
+	slices.Sort(names)
+
+	return slices.Compact(names)
+}
+
+func (env *Env) function(name string, arity uint) (*Function, bool) {
+	env.mu.RLock()
+	defer env.mu.RUnlock()
+
+	fn, ok := env.functions[functionKey(name, arity)]
+	return fn, ok
+}
+
+func (env *Env) functionArities(name string) []uint {
+	env.mu.RLock()
+	defer env.mu.RUnlock()
+
+	var arities []uint
+	for _, fn := range env.functions {
+		if fn.name == name {
+			arities = append(arities, uint(len(fn.params)))
+		}
+	}
+
+	return arities
+}
+
+func (env *Env) setFunction(fn *Function) error {
+	if err := env.checkAssignable(fn.name); err != nil {
+		return err
+	}
+	if env.calls(fn.key(), fn.calls, make(map[string]bool)) {
+		return fmt.Errorf("recursive function `%s`", fn.key())
+	}
+
+	env.mu.Lock()
+	defer env.mu.Unlock()
+
+	env.functions[fn.key()] = fn
+	return nil
+}
+
+// calls reports whether any of called functions directly or indirectly call function with the given key
+func (env *Env) calls(key string, calls []string, visited map[string]bool) bool {
+	for _, call := range calls {
+		if call == key {
+			return true
+		}
+		if visited[call] {
+			continue
+		}
+		visited[call] = true
+
+		env.mu.RLock()
+		fn, ok := env.functions[call]
+		env.mu.RUnlock()
+
+		if ok && env.calls(key, fn.calls, visited) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (fn *Function) key() string {
+	return functionKey(fn.name, uint(len(fn.params)))
+}
+
+func functionKey(name string, arity uint) string {
+	return name + "/" + strconv.FormatUint(uint64(arity), 10)
+}
+
+// scope holds values available during evaluation
+type scope struct {
+	env       *Env
+	args      []Value
+	intMode   IntMode
+	exact     bool // Number literals are rationals
+	complex   bool // Square roots and powers of negative numbers are complex
+	angle     AngleMode
+	precision int32 // Digits after the point intermediate results are rounded to
+}
+
+func variableIdentifier(name string) *Identifier {
+	return &Identifier{
+		text:     name,
+		name:     "user variable",
+		variable: true,
+		apply: func(s *scope, _ []Value) (Value, error) {
+			value, ok := s.env.Variable(name)
+			if !ok {
+				return nil, fmt.Errorf("undefined variable")
+			}
+			return value, nil
+		},
+	}
+}
+
+func paramIdentifier(name string, index int) *Identifier {
+	return &Identifier{
+		text:     name,
+		name:     "parameter",
+		variable: true,
+		apply: func(s *scope, _ []Value) (Value, error) {
+			return s.args[index], nil
+		},
+	}
+}
+
+func functionIdentifier(name string, arity uint) *Identifier {
+	return &Identifier{
+		text:  name,
+		name:  "user function",
+		arity: exactly(arity),
+		apply: func(s *scope, args []Value) (Value, error) {
+			fn, ok := s.env.function(name, arity)
+			if !ok {
+				return nil, fmt.Errorf("undefined function")
+			}
+
+			inner := *s
+			inner.args = args
+
+			result, err := evaluate(fn.body, &inner)
+			if err != nil {
+				var exprErr *ExprError
+				if errors.As(err, &exprErr) {
+					return nil, errors.New(exprErr.Message)
+				}
+				return nil, err
+			}
+
+			return result, nil
+		},
+	}
+}
+
+// columnSign starts reference to the column of input line, for example: `$1`
+const columnSign = "$"
+
+func columnIdentifier(name string, number int) *Identifier {
+	return &Identifier{
+		text:     name,
+		name:     "column",
+		variable: true,
+		apply: func(s *scope, _ []Value) (Value, error) {
+			text, ok := s.env.column(number)
+			if !ok {
+				return nil, fmt.Errorf("no column %d", number)
+			}
+
+			value, err := decimal.NewFromString(strings.TrimSpace(text))
+			if err != nil {
+				return nil, fmt.Errorf("column %d is not a number: `%s`", number, text)
+			}
+			if s.exact {
+				return newRational(value), nil
+			}
+			return NewNumber(value), nil
+		},
+	}
+}
+
+const unitIdentifierName = "unit"
+
+func unitIdentifier(name string, unit Unit) *Identifier {
+	return &Identifier{
+		text:     name,
+		name:     unitIdentifierName,
+		variable: true,
+		apply:    applyConstantIdent(Quantity{value: decimal.NewFromInt(1), unit: unit}),
+	}
+}
+
+func (env *Env) checkAssignable(name string) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+
+	for _, identifier := range slices.Concat(knownIdentifiers, env.hostIdentifiers()) {
+		if identifier.text == name {
+			if identifier.variable {
+				return fmt.Errorf("can't redefine constant `%s`", name)
+			}
+			return fmt.Errorf("can't redefine function `%s`", name)
+		}
+	}
+
+	return nil
+}
+
+// checkVariable checks that variable can be assigned, variables can't shadow units, for example: `m` or `km`
+func (env *Env) checkVariable(name string) error {
+	if err := env.checkAssignable(name); err != nil {
+		return err
+	}
+	if _, ok := lookupUnit(name); ok {
+		return fmt.Errorf("can't redefine unit `%s`", name)
+	}
+	return nil
+}
+
+func checkName(name string) error {
+	if name == "" || utils.IsDigit(name[0]) || !utils.IsWord(name) {
+		return fmt.Errorf("invalid name `%s`", name)
+	}
+	return nil
+}
@@ -15,8 +15,9 @@ func NewExprError(text string, loc Location) *ExprError {
 }
 
 func (e *ExprError) Error() string {
-	if e.Loc.Size() == 1 {
-		return fmt.Sprintf("expression at [%d]: %s", e.Loc.Start+1, e.Message)
+	// Locations that span several lines have no meaningful end column
+	if e.Loc.Width() <= 1 {
+		return fmt.Sprintf("expression at [%d]: %s", e.Loc.StartColumn+1, e.Message)
 	}
-	return fmt.Sprintf("expression in rage [%d, %d]: %s", e.Loc.Start+1, e.Loc.End, e.Message)
+	return fmt.Sprintf("expression in rage [%d, %d]: %s", e.Loc.StartColumn+1, e.Loc.EndColumn, e.Message)
 }
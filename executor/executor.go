@@ -1,11 +1,13 @@
 package executor
 
 import (
+	"errors"
 	"fmt"
 	"slices"
-	"strconv"
+	"strings"
+	"unicode/utf8"
 
-	"github.com/shopspring/decimal"
+	"github.com/mattn/go-runewidth"
 
 	"github.com/mymmrac/mm/debugger"
 	"github.com/mymmrac/mm/utils"
@@ -13,97 +15,496 @@ import (
 
 type Executor struct {
 	debugger *debugger.Debugger
+	env      *Env
+	base     int
+	intMode  IntMode
+	exact    ExactMode
+	complex  bool
+	angle    AngleMode
+	strict   bool
+	format   Format
 }
 
-func NewExecutor(debugger *debugger.Debugger) *Executor {
+// NewExecutor creates new executor, debugger and env are optional, if env is nil, new empty env is created
+func NewExecutor(debug *debugger.Debugger, env *Env) *Executor {
+	if debug == nil {
+		debug = &debugger.Debugger{}
+	}
+	if env == nil {
+		env = NewEnv()
+	}
+
 	return &Executor{
-		debugger: debugger,
+		debugger: debug,
+		env:      env,
+		base:     10,
+	}
+}
+
+// Env returns environment with host and user defined values
+func (e *Executor) Env() *Env {
+	return e.env
+}
+
+// SetBase sets base in which results are formatted, supported bases are 2, 8, 10 and 16
+func (e *Executor) SetBase(base int) error {
+	if baseNames[base] == "" {
+		return fmt.Errorf("unsupported base %d", base)
 	}
+	e.base = base
+	return nil
+}
+
+// Base returns base in which results are formatted
+func (e *Executor) Base() int {
+	return e.base
+}
+
+// SetIntMode sets integer mode that is used by programs compiled after it
+func (e *Executor) SetIntMode(mode IntMode) error {
+	if err := mode.validate(); err != nil {
+		return err
+	}
+	e.intMode = mode
+	return nil
+}
+
+// IntMode returns current integer mode
+func (e *Executor) IntMode() IntMode {
+	return e.intMode
+}
+
+// SetExactMode sets exact mode that is used by programs compiled after it, display of fractions is changed
+// immediately
+func (e *Executor) SetExactMode(mode ExactMode) error {
+	if err := mode.validate(); err != nil {
+		return err
+	}
+	e.exact = mode
+	return nil
+}
+
+// ExactMode returns current exact mode
+func (e *Executor) ExactMode() ExactMode {
+	return e.exact
+}
+
+// SetComplexMode enables or disables complex mode that is used by programs compiled after it, in complex mode square
+// roots and fractional powers of negative numbers result in complex numbers
+func (e *Executor) SetComplexMode(enabled bool) {
+	e.complex = enabled
+}
+
+// ComplexMode reports whether complex mode is enabled
+func (e *Executor) ComplexMode() bool {
+	return e.complex
+}
+
+// SetAngleMode sets angle mode that is used by programs compiled after it, trigonometric functions treat numbers as
+// angles in the mode and their inverses return angles in it
+func (e *Executor) SetAngleMode(mode AngleMode) error {
+	if err := mode.validate(); err != nil {
+		return err
+	}
+	e.angle = mode
+	return nil
+}
+
+// AngleMode returns current angle mode
+func (e *Executor) AngleMode() AngleMode {
+	return e.angle
+}
+
+// SetStrictMode enables or disables strict mode that is used by expressions compiled after it, in strict mode
+// implicit multiplication like `2Pi` or `3(4 + 1)` is not allowed and each multiplication must be written with `*`
+func (e *Executor) SetStrictMode(enabled bool) {
+	e.strict = enabled
 }
 
+// StrictMode reports whether strict mode is enabled
+func (e *Executor) StrictMode() bool {
+	return e.strict
+}
+
+// SetFormat sets format of decimal numbers in results, it is applied immediately
+func (e *Executor) SetFormat(format Format) error {
+	if err := format.validate(); err != nil {
+		return err
+	}
+	e.format = format
+	return nil
+}
+
+// Format returns format of decimal numbers in results
+func (e *Executor) Format() Format {
+	return e.format
+}
+
+// Execute evaluates expression or assignment statement, assigned values and defined functions are stored in
+// environment
 func (e *Executor) Execute(expression string, precision int32) (string, error) {
+	return e.execute(expression, precision, true)
+}
+
+// Evaluate evaluates expression or assignment statement like Execute, but returns value instead of formatted result,
+// nil value is returned for empty expressions and function definitions
+func (e *Executor) Evaluate(expression string, precision int32) (Value, error) {
+	return e.evaluate(expression, precision, true)
+}
+
+// Preview evaluates expression or assignment statement without modifying environment
+func (e *Executor) Preview(expression string, precision int32) (string, error) {
+	return e.execute(expression, precision, false)
+}
+
+func (e *Executor) execute(expression string, precision int32, commit bool) (string, error) {
+	value, err := e.evaluate(expression, precision, commit)
+	if err != nil || value == nil {
+		return "", err
+	}
+	return e.FormatValue(value, precision, e.format), nil
+}
+
+func (e *Executor) evaluate(expression string, precision int32, commit bool) (Value, error) {
+	if precision > MaxPrecision {
+		return nil, fmt.Errorf("precision must not be greater than %d", MaxPrecision)
+	}
+
+	program, err := e.compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	if program == nil {
+		return nil, nil
+	}
+
+	return e.runProgram(program, precision, commit)
+}
+
+// ExecuteScript evaluates statements separated by new lines or `;`, text after `#` is a comment, results of
+// expressions are returned in order, while assignments and function definitions have no results, execution stops at
+// the first error and results of statements before it are returned with the error
+func (e *Executor) ExecuteScript(script string, precision int32) ([]string, error) {
+	var results []string
+	for _, statement := range e.ExecuteStatements(script, precision) {
+		if statement.Err != nil {
+			return results, statement.Err
+		}
+		if statement.Result != "" {
+			results = append(results, statement.Result)
+		}
+	}
+	return results, nil
+}
+
+// StatementResult is a result of a statement of the script
+type StatementResult struct {
+	Text   string   // Text of the statement without comments and separators
+	Loc    Location // Location of the statement in the script
+	Result string   // Formatted result of expression, empty for assignments and function definitions
+	Err    error    // Error of the statement, its location is in the script
+}
+
+// ExecuteStatements evaluates statements of the script like ExecuteScript, but returns results of all statements
+// with their locations, execution stops at the first error, so only the last statement can have an error, if script
+// can't be split into statements, the only result with an error covers the whole script
+func (e *Executor) ExecuteStatements(script string, precision int32) []StatementResult {
+	whole := StatementResult{Text: script, Loc: Location{Start: 0, End: len(script)}}
+
+	if precision > MaxPrecision {
+		whole.Err = fmt.Errorf("precision must not be greater than %d", MaxPrecision)
+		return []StatementResult{whole}
+	}
+
+	tokens, err := e.tokenize(script)
+	if err != nil {
+		whole.Err = err
+		return []StatementResult{whole}
+	}
+
+	var results []StatementResult
+	for _, stmtTokens := range splitStatements(tokens) {
+		e.debugger.Clean()
+
+		loc := span(stmtTokens[0].loc, stmtTokens[len(stmtTokens)-1].loc)
+		statement := StatementResult{Text: script[loc.Start:loc.End], Loc: loc}
+
+		program, err := e.compileTokens(stmtTokens)
+		if err == nil {
+			var value Value
+			value, err = e.runProgram(program, precision, true)
+			if err == nil && program.target == nil && program.function == nil {
+				statement.Result = e.FormatValue(value, precision, e.format)
+			}
+		}
+
+		statement.Err = err
+		results = append(results, statement)
+		if err != nil {
+			break
+		}
+	}
+	return results
+}
+
+// runProgram evaluates program with the precision, function definitions have no value
+func (e *Executor) runProgram(program *Program, precision int32, commit bool) (Value, error) {
+	result, err := program.runWithPrecision(e.env, commit, precision)
+	if err != nil {
+		return nil, err
+	}
+	if program.function != nil {
+		return nil, nil
+	}
+	return result, nil
+}
+
+// FormatValue formats value rounded to the precision digits after the point in the base of results, exact mode of
+// executor and the format, negative numbers in other bases are written in two's complement of integer mode
+func (e *Executor) FormatValue(value Value, precision int32, format Format) string {
+	if e.base != 10 {
+		value = mapElements(value, func(v Value) Value {
+			if number, ok := v.(Number); ok {
+				return NewNumber(e.intMode.twosComplement(number.value))
+			}
+			return v
+		})
+	}
+
+	return formatValue(value, precision, e.base, e.exact, format)
+}
+
+// compile compiles statement into the program, nil program is returned for empty expression
+func (e *Executor) compile(expression string) (*Program, error) {
 	e.debugger.Clean()
 
 	tokens, err := e.tokenize(expression)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	e.debugger.Debug("Tokens ", tokens)
 
-	if len(tokens) == 0 {
-		return "", nil
+	statements := splitStatements(tokens)
+	switch len(statements) {
+	case 0:
+		return nil, nil
+	case 1:
+		return e.compileTokens(statements[0])
+	default:
+		return nil, NewExprError("expected single statement", statements[1][0].loc)
 	}
+}
 
-	err = e.typeCheck(tokens)
+// compileTokens compiles tokens of single statement into the program
+func (e *Executor) compileTokens(tokens []Token) (*Program, error) {
+	e.debugger.Debug("Tokens ", tokens)
+
+	stmt, err := e.parseStatement(tokens)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	e.debugger.Debug("Tokens (type checked) ", tokens)
 
-	tokens, err = e.convertToPostfixNotation(tokens)
-	if err != nil {
-		return "", err
+	var params []string
+	for _, param := range stmt.params {
+		params = append(params, param.text)
+	}
+
+	self := ""
+	if stmt.function {
+		self = functionKey(stmt.target.text, uint(len(params)))
 	}
-	e.debugger.Debug("Tokens (postfix notation) ", tokens)
 
-	result, err := e.evaluate(tokens)
+	root, calls, err := e.parse(stmt.body, params, self)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	e.debugger.Debug("AST ", root)
+
+	program := &Program{
+		env:     e.env,
+		target:  stmt.target,
+		root:    root,
+		intMode: e.intMode,
+		exact:   e.exact.Enabled(),
+		complex: e.complex,
+		angle:   e.angle,
+		format:  e.format,
+	}
+
+	if stmt.function {
+		program.function = &Function{
+			name:   stmt.target.text,
+			params: params,
+			body:   root,
+			calls:  calls,
+		}
 	}
 
-	return result.Round(precision).String(), nil
+	return program, nil
 }
 
-func (e *Executor) tokenize(expression string) ([]Token, error) {
-	i := 0
-	var tokens []Token
-	for i < len(expression) {
-		if utils.IsSpace(expression[i]) {
-			i++
+// splitStatements splits tokens by separators, new lines inside parentheses and brackets don't separate statements,
+// so long lists can be written on several lines, empty statements are dropped
+func splitStatements(tokens []Token) [][]Token {
+	var statements [][]Token
+	var current []Token
+	depth := 0
+	for _, token := range tokens {
+		switch {
+		case token.isOpenParenthesis() || token.isOpenBracket():
+			depth++
+		case token.isCloseParenthesis() || token.isCloseBracket():
+			depth = max(depth-1, 0)
+		case token.isSeparator():
+			if depth > 0 && token.text == "\n" {
+				continue
+			}
+			if len(current) != 0 {
+				statements = append(statements, current)
+			}
+			current, depth = nil, 0
 			continue
 		}
+		current = append(current, token)
+	}
+	if len(current) != 0 {
+		statements = append(statements, current)
+	}
+	return statements
+}
 
-		if utils.IsDigit(expression[i]) {
-			j := i + 1
-
-			hasDot := false
-			hasExp := false
-			hasExpSign := false
-			expSignPos := -1
-
-		numberLoop:
-			for j < len(expression) {
-				switch {
-				case utils.IsDigit(expression[j]):
-					j++
-				case !hasDot && expression[j] == '.':
-					hasDot = true
-					j++
-				case !hasExpSign && utils.IsInCharset(expression[j], "+-"):
-					hasExpSign = true
-					expSignPos = j
-					j++
-				case !hasExp && utils.IsInCharset(expression[j], "eE"):
-					hasExp = true
-					j++
-				default:
-					break numberLoop
+// statement is an expression, variable assignment `name = expr` or function definition `name(a, b) = expr`
+type statement struct {
+	target   *Token
+	function bool
+	params   []Token
+	body     []Token
+}
+
+func (e *Executor) parseStatement(tokens []Token) (statement, error) {
+	assignIndex := slices.IndexFunc(tokens, Token.isAssign)
+	if assignIndex == -1 {
+		return statement{body: tokens}, nil
+	}
+
+	stmt := statement{
+		target: &tokens[0],
+		body:   tokens[assignIndex+1:],
+	}
+	if stmt.target.kind != KindIdentifier {
+		return statement{}, NewExprError("expected identifier, but got `"+stmt.target.text+"`", stmt.target.loc)
+	}
+	checkAssignable := e.env.checkAssignable
+	if assignIndex == 1 {
+		checkAssignable = e.env.checkVariable
+	}
+	if err := checkAssignable(stmt.target.text); err != nil {
+		return statement{}, NewExprError(err.Error(), stmt.target.loc)
+	}
+
+	if assignIndex > 1 {
+		stmt.function = true
+
+		header := tokens[1:assignIndex]
+		if !header[0].isOpenParenthesis() {
+			return statement{}, NewExprError("expected `"+opAssign.text+"`, but got `"+header[0].text+"`", header[0].loc)
+		}
+		if len(header) < 2 || !header[len(header)-1].isCloseParenthesis() {
+			return statement{}, NewExprError(
+				"expected `"+opCloseParenthesis.text+"` before `"+opAssign.text+"`", tokens[assignIndex].loc,
+			)
+		}
+
+		for i, token := range header[1 : len(header)-1] {
+			if i%2 == 1 {
+				if !token.isComma() {
+					return statement{}, NewExprError("expected `"+opComma.text+"`, but got `"+token.text+"`", token.loc)
 				}
+				continue
 			}
 
-			if hasExpSign && !hasExp {
-				j = expSignPos
+			if token.kind != KindIdentifier {
+				return statement{}, NewExprError("expected parameter name, but got `"+token.text+"`", token.loc)
+			}
+			if err := e.env.checkAssignable(token.text); err != nil {
+				return statement{}, NewExprError(err.Error(), token.loc)
 			}
+			if slices.ContainsFunc(stmt.params, func(param Token) bool { return param.text == token.text }) {
+				return statement{}, NewExprError("duplicate parameter `"+token.text+"`", token.loc)
+			}
+
+			stmt.params = append(stmt.params, token)
+		}
+		if len(header) > 2 && header[len(header)-2].isComma() {
+			return statement{}, NewExprError("unexpected "+opComma.name, header[len(header)-2].loc)
+		}
+	}
 
-			tokens = append(tokens, Token{
-				text: expression[i:j],
-				loc: Location{
-					Start: i,
-					End:   j,
-				},
-				kind: KindNumber,
+	if len(stmt.body) == 0 {
+		return statement{}, NewExprError("expected expression after `"+opAssign.text+"`", tokens[assignIndex].loc)
+	}
+
+	return stmt, nil
+}
+
+func (e *Executor) tokenize(expression string) ([]Token, error) {
+	i, line, column := 0, 0, 0
+	var tokens []Token
+	add := func(text string, kind TokenKind, size int) {
+		width := runewidth.StringWidth(expression[i : i+size])
+		tokens = append(tokens, Token{
+			text: text,
+			kind: kind,
+			loc: Location{
+				Start:       i,
+				End:         i + size,
+				Line:        line,
+				StartColumn: column,
+				EndColumn:   column + width,
+			},
+		})
+		i += size
+		column += width
+	}
+
+	for i < len(expression) {
+		r, size := utf8.DecodeRuneInString(expression[i:])
+		if r == utf8.RuneError && size == 1 {
+			return nil, NewExprError("invalid UTF-8 encoding", Location{
+				Start: i, End: i + 1, Line: line, StartColumn: column, EndColumn: column + 1,
 			})
-			i = j
+		}
+
+		if r == '\n' || r == statementSeparator {
+			add(string(r), KindSeparator, size)
+			if r == '\n' {
+				line++
+				column = 0
+			}
+			continue
+		}
+
+		if r == commentSign {
+			// Comment ends with new line, so there is no need to track its columns
+			for i < len(expression) && expression[i] != '\n' {
+				i++
+			}
+			continue
+		}
+
+		if utils.IsSpace(r) {
+			i += size
+			column += runewidth.RuneWidth(r)
+			continue
+		}
+
+		if utils.IsDigit(expression[i]) {
+			j := scanNumber(expression, i)
+			add(expression[i:j], KindNumber, j-i)
+			continue
+		}
+
+		if alias, ok := symbolAliases[r]; ok {
+			add(alias.text, alias.kind, size)
 			continue
 		}
 
@@ -111,347 +512,248 @@ func (e *Executor) tokenize(expression string) ([]Token, error) {
 			if len(op)+i > len(expression) {
 				return false
 			}
+			// Word operators must not be followed by letters or digits
+			if utils.IsLetter(op[0]) && len(op)+i < len(expression) &&
+				(utils.IsLetter(expression[i+len(op)]) || utils.IsDigit(expression[i+len(op)])) {
+				return false
+			}
 			return expression[i:i+len(op)] == op
 		})
 		if opIndex != -1 {
 			op := knownUniqueOperators[opIndex]
-			tokens = append(tokens, Token{
-				text: op,
-				kind: KindOperator,
-				loc: Location{
-					Start: i,
-					End:   i + len(op),
-				},
-			})
-			i += len(op)
+			add(op, KindOperator, len(op))
 			continue
 		}
 
-		identIndex := slices.IndexFunc(knownUniqueIdentifiers, func(ident string) bool {
-			if len(ident)+i > len(expression) {
-				return false
-			}
-			return expression[i:i+len(ident)] == ident
-		})
-		if identIndex != -1 {
-			ident := knownUniqueIdentifiers[identIndex]
-			tokens = append(tokens, Token{
-				text: ident,
-				kind: KindIdentifier,
-				loc: Location{
-					Start: i,
-					End:   i + len(ident),
-				},
-			})
-			i += len(ident)
+		if ident := matchIdentifier(expression[i:]); ident != "" {
+			add(ident, KindIdentifier, len(ident))
 			continue
 		}
 
-		return nil, NewExprError("invalid symbol", Location{Start: i, End: i + 1})
+		return nil, NewExprError("invalid symbol", Location{
+			Start: i, End: i + size, Line: line, StartColumn: column, EndColumn: column + max(runewidth.RuneWidth(r), 1),
+		})
 	}
 	return tokens, nil
 }
 
-func (e *Executor) typeCheck(tokens []Token) error {
-	lValues := 0
-	lastLValue := -1
-	openParents := 0
-	lastOpenParentIndex := -1
+const (
+	statementSeparator = ';' // Separates statements in addition to new lines
+	commentSign        = '#' // Starts comment that lasts until the end of line
+)
 
-	for i, token := range tokens {
-		switch token.kind {
-		case KindNumber:
-			number, err := decimal.NewFromString(token.text)
-			if err != nil {
-				return fmt.Errorf("parse number: %w", err)
-			}
-			tokens[i].number = &number
-			lValues++
-			lastLValue = i
-		case KindOperator:
-			switch token.text {
-			case opOpenParenthesis.text:
-				openParents++
-				lastOpenParentIndex = i
-				tokens[i].operator = &opOpenParenthesis
-			case opCloseParenthesis.text:
-				if openParents == 0 {
-					return NewExprError("unexpected closing parenthesis", token.loc)
-				}
+// symbolAlias is a token that math symbol is replaced with
+type symbolAlias struct {
+	text string
+	kind TokenKind
+}
 
-				if tokens[i-1].isOpenParenthesis() && (i == 1 || (tokens[i-2].kind != KindIdentifier ||
-					tokens[i-2].identifier.arity != 0 || tokens[i-2].identifier.variable)) {
-					return NewExprError("unexpected closing parenthesis", token.loc)
-				}
+// symbolAliases are math symbols that are written instead of operators and identifiers, so that formulas copied from
+// documents can be evaluated as is, for example: `2 × π`
+var symbolAliases = map[rune]symbolAlias{
+	'π': {text: "Pi", kind: KindIdentifier},
+	'×': {text: "*", kind: KindOperator},
+	'÷': {text: "/", kind: KindOperator},
+	'−': {text: "-", kind: KindOperator},
+}
 
-				openParents--
-				tokens[i].operator = &opCloseParenthesis
-			case opComma.text:
-				// TODO: Check tha only used inside functions
-				tokens[i].operator = &opComma
-			default:
-				if i > 0 {
-					pt := tokens[i-1]
-					if pt.kind == KindOperator && !pt.isControlFlow() {
-						return NewExprError("unexpected operator `"+token.text+"`", token.loc)
-					}
-				}
+// matchIdentifier returns the degree sign, column reference or the longest word that expression starts with,
+// identifiers are resolved only by parser, so `Pie` is a single identifier rather than `Pi` followed by `e`
+func matchIdentifier(expression string) string {
+	if strings.HasPrefix(expression, degreeSign) {
+		return degreeSign
+	}
+	if strings.HasPrefix(expression, columnSign) {
+		j := len(columnSign)
+		for j < len(expression) && utils.IsDigit(expression[j]) {
+			j++
+		}
+		if j == len(columnSign) {
+			return ""
+		}
+		return expression[:j]
+	}
+	return matchWord(expression)
+}
 
-				opIndex := -1
-
-				if i == 0 || (tokens[i-1].kind == KindOperator && tokens[i-1].text != opCloseParenthesis.text) {
-					opIndex = slices.IndexFunc(knownOperators, func(op Operator) bool {
-						return op.arity == 1 && op.text == token.text
-					})
-				} else {
-					opIndex = slices.IndexFunc(knownOperators, func(op Operator) bool {
-						return op.arity == 2 && op.text == token.text
-					})
-					lValues--
-				}
+// matchWord returns word of letters and digits that expression starts with, word can't start with a digit
+func matchWord(expression string) string {
+	if !utils.IsLetter(expression[0]) {
+		return ""
+	}
 
-				if opIndex < 0 {
-					return NewExprError("unknown operator `"+token.text+"`", token.loc)
-				}
+	j := 1
+	for j < len(expression) && (utils.IsLetter(expression[j]) || utils.IsDigit(expression[j])) {
+		j++
+	}
+	return expression[:j]
+}
 
-				tokens[i].operator = &knownOperators[opIndex]
-			}
-		case KindIdentifier:
-			identIndex := -1
-
-			if i == len(tokens)-1 || !(tokens[i+1].isOpenParenthesis()) {
-				identIndex = slices.IndexFunc(knownIdentifiers, func(ident Identifier) bool {
-					return ident.variable && ident.text == token.text
-				})
-				if identIndex < 0 {
-					return NewExprError("unknown identifier `"+token.text+"`", token.loc)
-				}
-				lValues++
-				lastLValue = i
-			} else {
-				parenthesis := 0
-				var args uint = 0
-				capturingArg := false
-				var unusedComma *Token
-				for k, t := range tokens[i+1:] {
-					// Check first open parenthesis
-					if k == 0 {
-						if !t.isOpenParenthesis() {
-							return NewExprError(
-								"expected `"+opOpenParenthesis.text+"`, but got `"+t.text+"`",
-								t.loc,
-							)
-						}
-						parenthesis++
-						continue
-					}
-
-					// Add open parenthesis
-					if t.isOpenParenthesis() {
-						if !capturingArg {
-							args++
-							capturingArg = true
-							unusedComma = nil
-						}
-						parenthesis++
-						continue
-					}
-					// Remove open parenthesis
-					if t.isCloseParenthesis() {
-						parenthesis--
-						if parenthesis == 0 {
-							break
-						}
-						continue
-					}
-
-					// Skip everything inside parenthesis
-					if parenthesis > 1 {
-						continue
-					}
-
-					// Check comma
-					if t.isComma() {
-						unusedComma = &t
-						capturingArg = false
-						continue
-					}
-
-					// Add argument
-					if !capturingArg {
-						args++
-						capturingArg = true
-						unusedComma = nil
-					}
-				}
+func evaluate(n node, s *scope) (Value, error) {
+	result, err := evaluateNode(n, s)
+	if err != nil {
+		return nil, err
+	}
 
-				if unusedComma != nil {
-					return NewExprError("unexpected "+opComma.name, unusedComma.loc)
-				}
+	return wrapIntegers(result, s), nil
+}
 
-				identIndex = slices.IndexFunc(knownIdentifiers, func(ident Identifier) bool {
-					return !ident.variable && ident.arity == args && ident.text == token.text
-				})
-				if identIndex < 0 {
-					return NewExprError(
-						"unknown function `"+token.text+"/"+strconv.FormatUint(uint64(args), 10)+"`",
-						token.loc,
-					)
-				}
+// wrapIntegers truncates and wraps numbers around in integer mode
+func wrapIntegers(v Value, s *scope) Value {
+	if !s.intMode.Enabled() {
+		return v
+	}
 
-				lValues -= int(args)
-				lValues++
-				lastLValue = i
-			}
+	return mapElements(v, func(v Value) Value {
+		switch v.(type) {
+		case Number, Rational:
+			value, _ := toDecimal(v, s.precision)
+			return NewNumber(s.intMode.wrap(value))
+		}
+		return v
+	})
+}
 
-			tokens[i].identifier = &knownIdentifiers[identIndex]
-		default:
-			return NewExprError(fmt.Sprintf("unknown token kind: %q", token.kind), token.loc)
+func evaluateNode(n node, s *scope) (Value, error) {
+	switch n := n.(type) {
+	case *numberNode:
+		if s.intMode.Enabled() && !n.value.IsInteger() {
+			return nil, NewExprError("number must be an integer in integer mode", n.loc)
+		}
+		if s.exact {
+			return newRational(n.value), nil
+		}
+		return NewNumber(n.value), nil
+	case *identifierNode:
+		result, err := applyIdentifier(n.identifier, s, nil)
+		if err != nil {
+			return nil, wrapApplyError(err, "apply variable `"+n.identifier.text+"`", n.loc)
+		}
+		return result, nil
+	case *unaryNode:
+		return applyOperator(n.operator, n.opLoc, s, n.operand)
+	case *binaryNode:
+		return applyOperator(n.operator, n.opLoc, s, n.left, n.right)
+	case *listNode:
+		elements, err := evaluateArgs(n.elements, s)
+		if err != nil {
+			return nil, err
+		}
+		return List{elements: elements}, nil
+	case *indexNode:
+		args, err := evaluateArgs([]node{n.list, n.index}, s)
+		if err != nil {
+			return nil, err
 		}
-	}
 
-	if openParents != 0 {
-		return NewExprError("unexpected opening parenthesis", tokens[lastOpenParentIndex].loc)
-	}
-	switch {
-	case lValues < 0:
-		return NewExprError("too many values consumed in expression", Location{
-			Start: tokens[0].loc.Start,
-			End:   tokens[len(tokens)-1].loc.End,
-		})
-	case lValues == 0:
-		return NewExprError("no values returned in expression", Location{
-			Start: tokens[0].loc.Start,
-			End:   tokens[len(tokens)-1].loc.End,
-		})
-	case lValues == 1:
-		return nil
-	default:
-		// FIXME: last L value for functions with arguments is not correct
-		return NewExprError("too many values returned in expression", tokens[lastLValue].loc)
-	}
-}
-
-func (e *Executor) convertToPostfixNotation(tokens []Token) ([]Token, error) {
-	stack := utils.NewStack[Token]()
-	output := utils.NewStack[Token]()
-
-	for i := 0; i < len(tokens); i++ {
-		token := tokens[i]
-		switch token.kind {
-		case KindNumber:
-			output.Push(token)
-		case KindOperator:
-			switch token.text {
-			case opOpenParenthesis.text:
-				stack.Push(token)
-			case opCloseParenthesis.text:
-				for !stack.Empty() && !stack.Top().isOpenParenthesis() {
-					output.Push(stack.Pop())
-				}
-				_ = stack.Pop()
-			case opComma.text:
-				return nil, NewExprError("unexpected `"+opComma.text+"`", token.loc)
-			default:
-				for !stack.Empty() && !stack.Top().isOpenParenthesis() &&
-					token.operator.precedence <= stack.Top().operator.precedence {
-					output.Push(stack.Pop())
-				}
-				stack.Push(token)
-			}
-		case KindIdentifier:
-			if token.identifier.variable {
-				output.Push(token)
-			} else {
-				i += 2 // Skip identifier and open parenthesis
-				for j := uint(0); j < token.identifier.arity; j++ {
-					si := i
-
-					t := tokens[i]
-					if j != token.identifier.arity-1 {
-						for !t.isComma() {
-							i++
-							t = tokens[i]
-						}
-					} else {
-						p := 0
-						for {
-							switch {
-							case t.isOpenParenthesis():
-								p++
-							case t.isCloseParenthesis():
-								p--
-							}
-							if p == -1 {
-								break
-							}
-
-							i++
-							t = tokens[i]
-						}
-					}
-
-					arg, err := e.convertToPostfixNotation(tokens[si:i])
-					if err != nil {
-						return nil, err
-					}
-					if j != token.identifier.arity-1 {
-						i++ // Skip comma
-					}
-
-					output.Push(arg...)
-				}
-				// Skip close parenthesis (current token) done in the loop above
-				output.Push(token)
+		result, err := index(args[0], args[1], s.precision)
+		if err != nil {
+			return nil, NewExprError(fmt.Sprintf("index: %s", err), n.indexLoc)
+		}
+		return result, nil
+	case *callNode:
+		var result Value
+		var err error
+		if n.identifier.lazy != nil {
+			result, err = n.identifier.lazy(lazyArgs(n.args, s))
+		} else {
+			var args []Value
+			args, err = evaluateArgs(n.args, s)
+			if err != nil {
+				return nil, err
 			}
-		default:
-			return nil, NewExprError(fmt.Sprintf("unknown token kind: %q", token.kind), token.loc)
+			result, err = applyIdentifier(n.identifier, s, args)
 		}
+		if err != nil {
+			return nil, wrapApplyError(err, "apply function `"+n.identifier.text+"`", n.nameLoc)
+		}
+		return result, nil
+	default:
+		return nil, NewExprError(fmt.Sprintf("unknown node: %T", n), n.location())
 	}
+}
 
-	for !stack.Empty() {
-		output.Push(stack.Pop())
+func applyOperator(operator *Operator, loc Location, s *scope, operands ...node) (Value, error) {
+	var result Value
+	var err error
+	if operator.lazy != nil {
+		result, err = operator.lazy(lazyArgs(operands, s))
+	} else {
+		var args []Value
+		if operator.integer && s.intMode.Enabled() {
+			args, err = evaluateIntegerArgs(operands, s)
+		} else {
+			args, err = evaluateArgs(operands, s)
+		}
+		if err != nil {
+			return nil, err
+		}
+		result, err = operator.apply(s, args)
+	}
+	if err != nil {
+		return nil, wrapApplyError(err, "apply operator `"+operator.text+"`", loc)
 	}
+	return result, nil
+}
 
-	return output.Slice(), nil
+func applyIdentifier(identifier *Identifier, s *scope, args []Value) (Value, error) {
+	return identifier.apply(s, args)
+}
+
+func evaluateArgs(args []node, s *scope) ([]Value, error) {
+	values := make([]Value, len(args))
+	for i, arg := range args {
+		var err error
+		values[i], err = evaluate(arg, s)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
 }
 
-func (e *Executor) evaluate(tokens []Token) (decimal.Decimal, error) {
-	stack := utils.NewStack[decimal.Decimal]()
+// evaluateIntegerArgs evaluates arguments that must be integers, in integer mode they are checked before they are
+// truncated
+func evaluateIntegerArgs(args []node, s *scope) ([]Value, error) {
+	values := make([]Value, len(args))
+	for i, arg := range args {
+		value, err := evaluateNode(arg, s)
+		if err != nil {
+			return nil, err
+		}
 
-	for _, token := range tokens {
-		switch token.kind {
-		case KindNumber:
-			stack.Push(*token.number)
-		case KindOperator:
-			if err := token.operator.apply(stack); err != nil {
-				return decimal.Zero, NewExprError(
-					fmt.Sprintf("apply operator `"+token.text+"`: %s", err),
-					token.loc,
-				)
-			}
-		case KindIdentifier:
-			if err := token.identifier.apply(stack); err != nil {
-				identType := "function"
-				if token.identifier.variable {
-					identType = "variable"
-				}
-				return decimal.Zero, NewExprError(
-					fmt.Sprintf("apply %s `"+token.text+"`: %s", identType, err),
-					token.loc,
-				)
+		integer := true
+		mapElements(value, func(v Value) Value {
+			if number, err := toDecimal(v, s.precision); err == nil && !number.IsInteger() {
+				integer = false
 			}
-		default:
-			return decimal.Zero, NewExprError(fmt.Sprintf("unknown token kind: %q", token.kind), token.loc)
+			return v
+		})
+		if !integer {
+			return nil, NewExprError("operand must be an integer", arg.location())
 		}
+
+		values[i] = wrapIntegers(value, s)
 	}
+	return values, nil
+}
 
-	switch stack.Size() {
-	case 0:
-		return decimal.Zero, fmt.Errorf("no return values")
-	case 1:
-		return stack.Pop(), nil
-	default:
-		return decimal.Zero, fmt.Errorf("too many (%d) values returned", stack.Size())
+func lazyArgs(args []node, s *scope) []func() (Value, error) {
+	thunks := make([]func() (Value, error), len(args))
+	for i, arg := range args {
+		thunks[i] = func() (Value, error) {
+			return evaluate(arg, s)
+		}
+	}
+	return thunks
+}
+
+// wrapApplyError adds context and location to the error, errors that already have location (returned from lazily
+// evaluated arguments) are returned as is
+func wrapApplyError(err error, context string, loc Location) error {
+	var exprErr *ExprError
+	if errors.As(err, &exprErr) {
+		return err
 	}
+	return NewExprError(fmt.Sprintf("%s: %s", context, err), loc)
 }
@@ -1,9 +1,16 @@
 package executor_test
 
 import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"sync"
 	"testing"
 
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/mymmrac/mm/debugger"
 	"github.com/mymmrac/mm/executor"
@@ -47,10 +54,10 @@ func TestExecute(t *testing.T) {
 		"atan_two":          {expr: "atan(2)", result: "1.1071487177940905", err: false},
 		"atan_half":         {expr: "atan(0.5)", result: "0.4636476090008061", err: false},
 		"sin_half":          {expr: "1/sin(0.5)", result: "2.0858296429334882", err: false},
-		"tan_half":          {expr: "1/tan(0.5)", result: "1.830487721712452", err: false},
-		"atan_two_pi":       {expr: "atan(2*Pi)", result: "1.4129651365067377", err: false},
+		"tan_half":          {expr: "1/tan(0.5)", result: "1.8304877217124519", err: false},
+		"atan_two_pi":       {expr: "atan(2*Pi)", result: "1.4129651365067378", err: false},
 	}
-	e := executor.NewExecutor(&debugger.Debugger{})
+	e := executor.NewExecutor(&debugger.Debugger{}, nil)
 	for name, tc := range testcases {
 		t.Run(name, func(t *testing.T) {
 			result, err := e.Execute(tc.expr, 16)
@@ -64,3 +71,1801 @@ func TestExecute(t *testing.T) {
 		})
 	}
 }
+
+func TestElementary(t *testing.T) {
+	testcases := map[string]struct {
+		expr   string
+		result string
+		err    bool
+	}{
+		"ln":                {expr: "ln(2)", result: "0.6931471805599453", err: false},
+		"ln_e":              {expr: "ln(e)", result: "1", err: false},
+		"ln_one":            {expr: "ln(1)", result: "0", err: false},
+		"ln_large":          {expr: "ln(10^100)", result: "230.2585092994045684", err: false},
+		"ln_complex":        {expr: "ln(2i)", result: "0.6931471805599453+1.5707963267948966i", err: false},
+		"log10":             {expr: "log10(1000)", result: "3", err: false},
+		"log10_two":         {expr: "log10(2)", result: "0.3010299956639812", err: false},
+		"log":               {expr: "log(8, 2)", result: "3", err: false},
+		"exp":               {expr: "exp(1)", result: "2.7182818284590452", err: false},
+		"exp_negative":      {expr: "exp(-1)", result: "0.3678794411714423", err: false},
+		"exp_large":         {expr: "exp(100)", result: "26881171418161354484126255515800135873611118.7737419224151916", err: false},
+		"exp_tiny":          {expr: "exp(-20000)", result: "0", err: false},
+		"exp_complex":       {expr: "exp(i*Pi)", result: "-1", err: false},
+		"sin":               {expr: "sin(Pi/6)", result: "0.5", err: false},
+		"sin_large":         {expr: "sin(10^6)", result: "-0.349993502171293", err: false},
+		"cos":               {expr: "cos(Pi/3)", result: "0.5", err: false},
+		"tan":               {expr: "tan(Pi/4)", result: "1", err: false},
+		"asin":              {expr: "asin(0.5)", result: "0.5235987755982989", err: false},
+		"asin_one":          {expr: "asin(1)", result: "1.5707963267948966", err: false},
+		"acos":              {expr: "acos(0.5)", result: "1.0471975511965977", err: false},
+		"acos_minus_one":    {expr: "acos(-1)", result: "3.1415926535897932", err: false},
+		"atan2":             {expr: "atan2(1, -1)", result: "2.3561944901923449", err: false},
+		"atan2_negative":    {expr: "atan2(-1, -1)", result: "-2.3561944901923449", err: false},
+		"atan2_zero":        {expr: "atan2(0, 0)", result: "0", err: false},
+		"sinh":              {expr: "sinh(1)", result: "1.1752011936438015", err: false},
+		"sinh_negative":     {expr: "sinh(-2)", result: "-3.6268604078470188", err: false},
+		"cosh":              {expr: "cosh(1)", result: "1.5430806348152438", err: false},
+		"tanh":              {expr: "tanh(1)", result: "0.7615941559557649", err: false},
+		"tanh_large":        {expr: "tanh(-30000)", result: "-1", err: false},
+		"asinh":             {expr: "asinh(1)", result: "0.881373587019543", err: false},
+		"asinh_negative":    {expr: "asinh(-1)", result: "-0.881373587019543", err: false},
+		"acosh":             {expr: "acosh(2)", result: "1.3169578969248167", err: false},
+		"atanh":             {expr: "atanh(0.5)", result: "0.5493061443340548", err: false},
+		"deg":               {expr: "deg(Pi)", result: "180", err: false},
+		"deg_one":           {expr: "deg(1)", result: "57.2957795130823209", err: false},
+		"cbrt":              {expr: "cbrt(27)", result: "3", err: false},
+		"cbrt_negative":     {expr: "cbrt(-8)", result: "-2", err: false},
+		"cbrt_two":          {expr: "cbrt(2)", result: "1.2599210498948732", err: false},
+		"nthroot":           {expr: "nthroot(16, 4)", result: "2", err: false},
+		"nthroot_odd":       {expr: "nthroot(-32, 5)", result: "-2", err: false},
+		"nthroot_large":     {expr: "nthroot(10^30, 3)", result: "10000000000", err: false},
+		"nthroot_negative":  {expr: "nthroot(4, -2)", result: "0.5", err: false},
+		"hypot":             {expr: "hypot(3, 4)", result: "5", err: false},
+		"hypot_units":       {expr: "hypot(3 m, 40 cm)", result: "3.0265491900843112 m", err: false},
+		"sign":              {expr: "sign(-5)", result: "-1", err: false},
+		"sign_zero":         {expr: "sign(0)", result: "0", err: false},
+		"sign_units":        {expr: "sign(2 km)", result: "1", err: false},
+		"trunc":             {expr: "trunc(-2.7)", result: "-2", err: false},
+		"trunc_units":       {expr: "trunc(2.5 km)", result: "2 km", err: false},
+		"frac":              {expr: "frac(-2.75)", result: "-0.75", err: false},
+		"ln_zero":           {expr: "ln(0)", result: "", err: true},
+		"ln_negative":       {expr: "ln(-1)", result: "", err: true},
+		"log_base_one":      {expr: "log(2, 1)", result: "", err: true},
+		"log_base_negative": {expr: "log(2, -2)", result: "", err: true},
+		"exp_too_large":     {expr: "exp(10001)", result: "", err: true},
+		"asin_domain":       {expr: "asin(2)", result: "", err: true},
+		"acos_domain":       {expr: "acos(-1.5)", result: "", err: true},
+		"acosh_domain":      {expr: "acosh(0.5)", result: "", err: true},
+		"atanh_domain":      {expr: "atanh(1)", result: "", err: true},
+		"nthroot_even":      {expr: "nthroot(-16, 4)", result: "", err: true},
+		"nthroot_fraction":  {expr: "nthroot(8, 1.5)", result: "", err: true},
+		"nthroot_zero":      {expr: "nthroot(8, 0)", result: "", err: true},
+		"hypot_dimensions":  {expr: "hypot(1 m, 1 s)", result: "", err: true},
+		"sign_complex":      {expr: "sign(i)", result: "", err: true},
+		"log10_list":        {expr: "log10([1])", result: "", err: true},
+	}
+	e := executor.NewExecutor(nil, nil)
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			result, err := e.Execute(tc.expr, 16)
+			if tc.err {
+				assert.Error(t, err)
+				assert.Equal(t, "", result)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.result, result)
+			}
+		})
+	}
+
+	_, err := e.Execute("ln(0)", 16)
+	assert.EqualError(t, err, "expression in rage [1, 2]: apply function `ln`: logarithm of zero")
+
+	result, err := e.Execute("exp(1)", 30)
+	require.NoError(t, err)
+	assert.Equal(t, "2.718281828459045235360287471353", result)
+
+	require.NoError(t, e.SetExactMode(executor.ExactFraction))
+	result, err = e.Execute("frac(7/2) + trunc(-7/2)", 16)
+	require.NoError(t, err)
+	assert.Equal(t, "-5/2", result)
+}
+
+func TestPrecision(t *testing.T) {
+	testcases := map[string]struct {
+		expr      string
+		precision int32
+		result    string
+	}{
+		"sqrt":     {expr: "sqrt(2)", precision: 60, result: "1.41421356237309504880168872420969807856967187537694807317668"},
+		"pi":       {expr: "Pi", precision: 60, result: "3.141592653589793238462643383279502884197169399375105820974945"},
+		"division": {expr: "1/7", precision: 60, result: "0.142857142857142857142857142857142857142857142857142857142857"},
+		"sin":      {expr: "sin(1)", precision: 60, result: "0.841470984807896506652502321630298999622563060798371065672752"},
+		"tan":      {expr: "tan(1)", precision: 60, result: "1.557407724654902230506974807458360173087250772381520038383947"},
+		"ln":       {expr: "ln(2)", precision: 60, result: "0.69314718055994530941723212145817656807550013436025525412068"},
+		"e":        {expr: "e", precision: 60, result: "2.718281828459045235360287471352662497757247093699959574966968"},
+		"power":    {expr: "3^1.5", precision: 60, result: "5.196152422706631880582339024517617100828415761431141884167421"},
+		"log":      {expr: "log10(100)", precision: 60, result: "2"},
+		"units":    {expr: "5 km / 1 mi", precision: 40, result: "3.1068559611866698480871709218165911079297"},
+		"angle":    {expr: "30 deg to rad", precision: 40, result: "0.5235987755982988730771072305465838140329 rad"},
+		"pi_long":  {expr: "Pi", precision: 64, result: "3.1415926535897932384626433832795028841971693993751058209749445923"},
+		"low":      {expr: "1/3", precision: 2, result: "0.33"},
+		"default":  {expr: "sqrt(2)", precision: 16, result: "1.414213562373095"},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			e := executor.NewExecutor(nil, nil)
+			result, err := e.Execute(tc.expr, tc.precision)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.result, result)
+		})
+	}
+
+	e := executor.NewExecutor(nil, nil)
+	result, err := e.Execute("sqrt(2)^2", executor.MaxPrecision)
+	require.NoError(t, err)
+	assert.Equal(t, "2", result)
+
+	_, err = e.Execute("1", executor.MaxPrecision+1)
+	assert.Error(t, err)
+}
+
+func TestAssignment(t *testing.T) {
+	e := executor.NewExecutor(&debugger.Debugger{}, nil)
+
+	result, err := e.Execute("x = 3.5", 16)
+	assert.NoError(t, err)
+	assert.Equal(t, "3.5", result)
+
+	result, err = e.Execute("x * 2", 16)
+	assert.NoError(t, err)
+	assert.Equal(t, "7", result)
+
+	result, err = e.Execute("y = x + 1", 16)
+	assert.NoError(t, err)
+	assert.Equal(t, "4.5", result)
+
+	result, err = e.Execute("x = x * y", 16)
+	assert.NoError(t, err)
+	assert.Equal(t, "15.75", result)
+
+	result, err = e.Preview("x = 0", 16)
+	assert.NoError(t, err)
+	assert.Equal(t, "0", result)
+
+	result, err = e.Execute("x", 16)
+	assert.NoError(t, err)
+	assert.Equal(t, "15.75", result)
+
+	errcases := map[string]string{
+		"constant":      "Pi = 3",
+		"function":      "sin = 1",
+		"number":        "1 = 2",
+		"no_expression": "z =",
+		"undefined":     "z = w + 1",
+		"double_assign": "z = w = 1",
+		"not_statement": "1 + z = 2",
+	}
+	for name, expr := range errcases {
+		t.Run(name, func(t *testing.T) {
+			result, err = e.Execute(expr, 16)
+			assert.Error(t, err)
+			assert.Equal(t, "", result)
+		})
+	}
+
+	_, ok := e.Env().Variable("z")
+	assert.False(t, ok)
+}
+
+func TestFunctions(t *testing.T) {
+	e := executor.NewExecutor(&debugger.Debugger{}, nil)
+
+	setup := []string{
+		"f(x, y) = x^2 + y",
+		"g(x) = f(x, 1) * 2",
+		"h() = 42",
+		"a = 10",
+		"k(x) = x + a",
+		"sq(x) = x * x",
+		"sq(x, y) = x * y",
+	}
+	for _, expr := range setup {
+		_, err := e.Execute(expr, 16)
+		require.NoError(t, err, expr)
+	}
+
+	testcases := map[string]struct {
+		expr   string
+		result string
+		err    bool
+	}{
+		"call":             {expr: "f(2, 3)", result: "7", err: false},
+		"nested_call":      {expr: "g(3)", result: "20", err: false},
+		"call_in_args":     {expr: "f(g(1), h())", result: "58", err: false},
+		"nullary":          {expr: "h()", result: "42", err: false},
+		"global":           {expr: "k(1)", result: "11", err: false},
+		"overload_one":     {expr: "sq(3)", result: "9", err: false},
+		"overload_two":     {expr: "sq(3, 4)", result: "12", err: false},
+		"wrong_arity":      {expr: "f(1)", result: "", err: true},
+		"param_not_global": {expr: "x", result: "", err: true},
+		"recursion":        {expr: "r(x) = r(x - 1)", result: "", err: true},
+		"indirect_rec":     {expr: "f(x, y) = g(x)", result: "", err: true},
+		"builtin":          {expr: "sin(x) = x", result: "", err: true},
+		"dup_param":        {expr: "d(x, x) = x", result: "", err: true},
+		"const_param":      {expr: "d(Pi) = Pi", result: "", err: true},
+		"bad_param":        {expr: "d(1) = 1", result: "", err: true},
+		"unknown_in_body":  {expr: "d(x) = y", result: "", err: true},
+		"eval_error":       {expr: "z(x) = 1 / x", result: "", err: false},
+		"eval_error_call":  {expr: "z(0)", result: "", err: true},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			result, err := e.Execute(tc.expr, 16)
+			if tc.err {
+				assert.Error(t, err)
+				assert.Equal(t, "", result)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.result, result)
+			}
+		})
+	}
+
+	_, err := e.Execute("f(1)", 16)
+	assert.EqualError(t, err, "expression at [1]: function `f` expects 2 arguments, but got 1")
+
+	_, err = e.Execute("sin(1, 2)", 16)
+	assert.EqualError(t, err, "expression in rage [1, 3]: function `sin` expects 1 argument, but got 2")
+
+	result, err := e.Execute("a = 20", 16)
+	require.NoError(t, err)
+	assert.Equal(t, "20", result)
+
+	result, err = e.Execute("k(1)", 16)
+	require.NoError(t, err)
+	assert.Equal(t, "21", result)
+}
+
+func TestVariadic(t *testing.T) {
+	testcases := map[string]struct {
+		expr   string
+		result string
+		err    bool
+	}{
+		"min":           {expr: "min(4, 2, 3, 1)", result: "1", err: false},
+		"max":           {expr: "max(1, 2, 3, 4)", result: "4", err: false},
+		"min_single":    {expr: "min(5)", result: "5", err: false},
+		"max_units":     {expr: "max(1 km, 500 m, 2000 m)", result: "2000 m", err: false},
+		"sum":           {expr: "sum(1, 2, 3)", result: "6", err: false},
+		"sum_single":    {expr: "sum(5)", result: "5", err: false},
+		"sum_lists":     {expr: "sum([1, 2], [3, 4])", result: "[4, 6]", err: false},
+		"avg":           {expr: "avg(1, 2, 3, 4)", result: "2.5", err: false},
+		"avg_list":      {expr: "avg([1, 2, 3])", result: "2", err: false},
+		"median":        {expr: "median(5, 1, 3)", result: "3", err: false},
+		"stddev":        {expr: "stddev(1, 2, 3)", result: "1", err: false},
+		"gcd":           {expr: "gcd(12, 18, 27)", result: "3", err: false},
+		"gcd_single":    {expr: "gcd(-4)", result: "4", err: false},
+		"gcd_list":      {expr: "gcd([8, 12])", result: "4", err: false},
+		"gcd_zero":      {expr: "gcd(0, 5)", result: "5", err: false},
+		"round_one":     {expr: "round(2.5)", result: "3", err: false},
+		"round_two":     {expr: "round(2.345, 2)", result: "2.35", err: false},
+		"round_up":      {expr: "roundUp(2.341, 2)", result: "2.35", err: false},
+		"nested":        {expr: "max(min(3, 1, 2), sum(1, 1), 0)", result: "2", err: false},
+		"no_args":       {expr: "min()", result: "", err: true},
+		"gcd_fraction":  {expr: "gcd(1.5, 3)", result: "", err: true},
+		"round_three":   {expr: "round(1, 2, 3)", result: "", err: true},
+		"stddev_single": {expr: "stddev(1)", result: "", err: true},
+	}
+	e := executor.NewExecutor(nil, nil)
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			result, err := e.Execute(tc.expr, 16)
+			if tc.err {
+				assert.Error(t, err)
+				assert.Equal(t, "", result)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.result, result)
+			}
+		})
+	}
+
+	_, err := e.Execute("max()", 16)
+	assert.EqualError(t, err, "expression in rage [1, 3]: function `max` expects at least 1 argument, but got 0")
+
+	_, err = e.Execute("round(1, 2, 3)", 16)
+	assert.EqualError(t, err, "expression in rage [1, 5]: function `round` expects 1 to 2 arguments, but got 3")
+
+	require.NoError(t, e.SetExactMode(executor.ExactFraction))
+	result, err := e.Execute("gcd(6, 4)", 16)
+	require.NoError(t, err)
+	assert.Equal(t, "2", result)
+}
+
+func TestFunctionsDoc(t *testing.T) {
+	readme, err := os.ReadFile("../README.md")
+	require.NoError(t, err)
+
+	for _, fn := range executor.Functions() {
+		assert.Contains(t, string(readme), "- `"+fn.Signature+"` "+fn.Description+"\n", "run `go generate`")
+	}
+}
+
+func TestHostEnv(t *testing.T) {
+	env := executor.NewEnv()
+
+	require.NoError(t, env.DefineConstant("g", decimal.RequireFromString("9.81")))
+
+	counter := decimal.Zero
+	require.NoError(t, env.DefineVariable("counter", func() (decimal.Decimal, error) {
+		counter = counter.Add(decimal.NewFromInt(1))
+		return counter, nil
+	}))
+
+	require.NoError(t, env.DefineFunction("double", 1, func(args []decimal.Decimal) (decimal.Decimal, error) {
+		return args[0].Mul(decimal.NewFromInt(2)), nil
+	}))
+	require.NoError(t, env.DefineFunction("fail", 0, func(_ []decimal.Decimal) (decimal.Decimal, error) {
+		return decimal.Zero, errors.New("host failure")
+	}))
+	require.NoError(t, env.DefineVariadicFunction("total", 1, func(args []decimal.Decimal) (decimal.Decimal, error) {
+		return decimal.Sum(args[0], args[1:]...), nil
+	}))
+
+	assert.Error(t, env.DefineConstant("Pi", decimal.Zero))
+	assert.Error(t, env.DefineConstant("g", decimal.Zero))
+	assert.Error(t, env.DefineConstant("1g", decimal.Zero))
+	assert.Error(t, env.DefineFunction("total", 3, func(_ []decimal.Decimal) (decimal.Decimal, error) {
+		return decimal.Zero, nil
+	}))
+	assert.EqualError(t, env.DefineFunction("max", 2, func(_ []decimal.Decimal) (decimal.Decimal, error) {
+		return decimal.Zero, nil
+	}), "can't redefine built-in identifier `max`")
+
+	e := executor.NewExecutor(nil, env)
+
+	testcases := []struct {
+		expr   string
+		result string
+		err    bool
+	}{
+		{expr: "g * 2", result: "19.62", err: false},
+		{expr: "counter", result: "1", err: false},
+		{expr: "counter + counter", result: "5", err: false},
+		{expr: "double(g)", result: "19.62", err: false},
+		{expr: "total(1)", result: "1", err: false},
+		{expr: "total(1, 2, 3, double(2))", result: "10", err: false},
+		{expr: "total()", result: "", err: true},
+		{expr: "double(1, 2)", result: "", err: true},
+		{expr: "fail()", result: "", err: true},
+		{expr: "g = 1", result: "", err: true},
+		{expr: "double(x) = x * 2", result: "", err: true},
+		{expr: "f(g) = g", result: "", err: true},
+		{expr: "f(x) = total(x, g)", result: "", err: false},
+		{expr: "f(1)", result: "10.81", err: false},
+	}
+	for _, tc := range testcases {
+		result, err := e.Execute(tc.expr, 16)
+		if tc.err {
+			assert.Error(t, err, tc.expr)
+			assert.Equal(t, "", result, tc.expr)
+		} else {
+			assert.NoError(t, err, tc.expr)
+			assert.Equal(t, tc.result, result, tc.expr)
+		}
+	}
+
+	_, err := e.Execute("1 + fail()", 16)
+	assert.EqualError(t, err, "expression in rage [5, 8]: apply function `fail`: host failure")
+
+	_, err = e.Execute("total()", 16)
+	assert.EqualError(t, err, "expression in rage [1, 5]: function `total` expects at least 1 argument, but got 0")
+}
+
+func TestCompile(t *testing.T) {
+	e := executor.NewExecutor(nil, nil)
+	require.NoError(t, e.Env().SetVariable("x", executor.NewNumber(decimal.Zero)))
+
+	program, err := e.Compile("x^2 + 2*x + 1")
+	require.NoError(t, err)
+
+	env := executor.NewEnv()
+	for i := int64(0); i < 5; i++ {
+		require.NoError(t, env.SetVariable("x", executor.NewNumber(decimal.NewFromInt(i))))
+
+		result, runErr := program.Run(env)
+		require.NoError(t, runErr)
+		assert.Equal(t, decimal.NewFromInt((i+1)*(i+1)).String(), result.String())
+	}
+
+	result, err := program.Run(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "1", result.String())
+
+	_, err = program.Run(executor.NewEnv())
+	assert.Error(t, err)
+
+	_, err = e.Compile("")
+	assert.Error(t, err)
+
+	_, err = e.Compile("y + 1")
+	assert.Error(t, err)
+
+	assign, err := e.Compile("y = x + 1")
+	require.NoError(t, err)
+	result, err = assign.Run(env)
+	require.NoError(t, err)
+	assert.Equal(t, "5", result.String())
+	y, ok := env.Variable("y")
+	assert.True(t, ok)
+	assert.Equal(t, "5", y.String())
+
+	root, err := e.Compile("sqrt(2)")
+	require.NoError(t, err)
+	result, err = root.RunWithPrecision(nil, 60)
+	require.NoError(t, err)
+	assert.Equal(t, "1.41421356237309504880168872420969807856967187537694807317668",
+		e.FormatValue(result, 60, executor.Format{}))
+
+	_, err = root.RunWithPrecision(nil, executor.MaxPrecision+1)
+	assert.Error(t, err)
+
+	significant := executor.Format{SignificantDigits: 3}
+	require.NoError(t, e.SetFormat(significant))
+	tiny, err := e.Compile("1/(3*10^40)")
+	require.NoError(t, err)
+	require.NoError(t, e.SetFormat(executor.Format{}))
+	result, err = tiny.RunWithPrecision(nil, 16)
+	require.NoError(t, err)
+	assert.Equal(t, "0."+strings.Repeat("0", 40)+"333", e.FormatValue(result, 16, significant))
+
+	t.Run("concurrent", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := int64(0); i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				localEnv := executor.NewEnv()
+				assert.NoError(t, localEnv.SetVariable("x", executor.NewNumber(decimal.NewFromInt(i))))
+				for range 100 {
+					r, runErr := program.Run(localEnv)
+					assert.NoError(t, runErr)
+					assert.Equal(t, decimal.NewFromInt((i+1)*(i+1)).String(), r.String())
+				}
+			}()
+		}
+		wg.Wait()
+	})
+}
+
+const benchmarkExpr = "(x + 1) * (x - 2) / 3 + abs(x - 5) * 2 - x % 7 + max(x, 50) // 4"
+
+func BenchmarkExecute(b *testing.B) {
+	e := executor.NewExecutor(nil, nil)
+
+	for i := 0; i < b.N; i++ {
+		_ = e.Env().SetVariable("x", executor.NewNumber(decimal.NewFromInt(int64(i%100))))
+		if _, err := e.Execute(benchmarkExpr, 16); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkProgramRun(b *testing.B) {
+	e := executor.NewExecutor(nil, nil)
+	_ = e.Env().SetVariable("x", executor.NewNumber(decimal.Zero))
+
+	program, err := e.Compile(benchmarkExpr)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = e.Env().SetVariable("x", executor.NewNumber(decimal.NewFromInt(int64(i%100))))
+		if _, err = program.Run(nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	testcases := map[string]struct {
+		expr   string
+		result string
+		err    string
+	}{
+		"nested_args_first":  {expr: "max(max(1, 2), 3)", result: "3"},
+		"nested_args_last":   {expr: "max(1, max(2, 3))", result: "3"},
+		"nested_deep":        {expr: "min(max(1, min(5, 4)), round(2.5, 0) + 1)", result: "4"},
+		"left_associative":   {expr: "8 - 4 - 2", result: "2"},
+		"unary_precedence":   {expr: "-2^2", result: "4"},
+		"unary_in_args":      {expr: "max(-1, -2)", result: "-1"},
+		"nullary_call":       {expr: "floor(rand())", result: "0"},
+		"extra_value":        {expr: "1 + 2 3", err: "expression at [7]: expected operator, but got `3`"},
+		"extra_identifier":   {expr: "sin(1) Pi", err: "expression in rage [8, 9]: expected operator, but got `Pi`"},
+		"missing_operand":    {expr: "1 +", err: "expression at [3]: expected value after `+`"},
+		"double_operator":    {expr: "1 * -2", err: "expression at [5]: unexpected operator `-`"},
+		"unclosed":           {expr: "(1 + 2", err: "expression at [1]: unexpected opening parenthesis"},
+		"unclosed_call":      {expr: "max(1, 2", err: "expression at [4]: unexpected opening parenthesis"},
+		"unopened":           {expr: "1 + 2)", err: "expression at [6]: unexpected closing parenthesis"},
+		"empty_parenthesis":  {expr: "1 + ()", err: "expression at [6]: unexpected closing parenthesis"},
+		"trailing_comma":     {expr: "max(1, )", err: "expression at [6]: unexpected comma"},
+		"comma_outside_call": {expr: "(1, 2)", err: "expression at [3]: unexpected comma"},
+	}
+	e := executor.NewExecutor(nil, nil)
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			result, err := e.Execute(tc.expr, 16)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.result, result)
+			}
+		})
+	}
+}
+
+func TestConditions(t *testing.T) {
+	testcases := map[string]struct {
+		expr   string
+		result string
+		err    bool
+	}{
+		"equal":            {expr: "0.1 + 0.2 == 0.3", result: "1", err: false},
+		"not_equal":        {expr: "1 != 1", result: "0", err: false},
+		"less":             {expr: "1 < 2", result: "1", err: false},
+		"less_equal":       {expr: "2 <= 2", result: "1", err: false},
+		"greater":          {expr: "1 > 2", result: "0", err: false},
+		"greater_equal":    {expr: "1 >= 2", result: "0", err: false},
+		"arithmetic_first": {expr: "1 + 2 == 6 / 2", result: "1", err: false},
+		"compare_first":    {expr: "1 < 2 == 2 < 3", result: "1", err: false},
+		"and":              {expr: "1 < 2 && 3 > 2", result: "1", err: false},
+		"and_false":        {expr: "1 && 0", result: "0", err: false},
+		"or":               {expr: "0 || 5", result: "1", err: false},
+		"or_false":         {expr: "0 || 0", result: "0", err: false},
+		"and_before_or":    {expr: "1 || 0 && 0", result: "1", err: false},
+		"not":              {expr: "!0", result: "1", err: false},
+		"not_not":          {expr: "!(!3)", result: "1", err: false},
+		"not_compare":      {expr: "!(1 > 2)", result: "1", err: false},
+		"and_short":        {expr: "0 && 1 / 0", result: "0", err: false},
+		"or_short":         {expr: "1 || 1 / 0", result: "1", err: false},
+		"and_error":        {expr: "1 && 1 / 0", result: "", err: true},
+		"if_true":          {expr: "if(2 > 1, 10, 20)", result: "10", err: false},
+		"if_false":         {expr: "if(2 < 1, 10, 20)", result: "20", err: false},
+		"if_lazy":          {expr: "if(1, 5, 1 / 0)", result: "5", err: false},
+		"if_lazy_else":     {expr: "if(0, sqrt(-1), 7)", result: "7", err: false},
+		"if_error":         {expr: "if(1, 1 / 0, 7)", result: "", err: true},
+		"if_nested":        {expr: "if(0, 1, if(1, 2, 3))", result: "2", err: false},
+		"if_arity":         {expr: "if(1, 2)", result: "", err: true},
+	}
+	e := executor.NewExecutor(nil, nil)
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			result, err := e.Execute(tc.expr, 16)
+			if tc.err {
+				assert.Error(t, err)
+				assert.Equal(t, "", result)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.result, result)
+			}
+		})
+	}
+
+	_, err := e.Execute("if(1, 2 / 0, 3)", 16)
+	assert.EqualError(t, err, "expression at [9]: apply operator `/`: division by zero")
+
+	_, err = e.Execute("magnitude(x) = if(x < 0, 0 - x, x)", 16)
+	require.NoError(t, err)
+	result, err := e.Execute("magnitude(0 - 3) + magnitude(2)", 16)
+	require.NoError(t, err)
+	assert.Equal(t, "5", result)
+}
+
+func TestBase(t *testing.T) {
+	literals := map[string]struct {
+		expr   string
+		result string
+		err    bool
+	}{
+		"hex":             {expr: "0xFF", result: "255", err: false},
+		"hex_lower":       {expr: "0xff + 0X1", result: "256", err: false},
+		"binary":          {expr: "0b1010", result: "10", err: false},
+		"octal":           {expr: "0o755", result: "493", err: false},
+		"separators":      {expr: "1_000_000", result: "1000000", err: false},
+		"separators_hex":  {expr: "0xFF_FF", result: "65535", err: false},
+		"separators_frac": {expr: "1_000.000_1", result: "1000.0001", err: false},
+		"exponent":        {expr: "1.5e3", result: "1500", err: false},
+		"exponent_sign":   {expr: "1-2e-3", result: "0.998", err: false},
+		"invalid_binary":  {expr: "0b102", result: "", err: true},
+		"invalid_octal":   {expr: "0o8", result: "", err: true},
+		"empty_hex":       {expr: "0x", result: "", err: true},
+		"double_sep":      {expr: "1__0", result: "", err: true},
+		"trailing_sep":    {expr: "10_", result: "", err: true},
+		"sep_after_dot":   {expr: "1._5", result: "", err: true},
+		"sep_after_base":  {expr: "0x_F", result: "", err: true},
+	}
+	e := executor.NewExecutor(nil, nil)
+	for name, tc := range literals {
+		t.Run(name, func(t *testing.T) {
+			result, err := e.Execute(tc.expr, 16)
+			if tc.err {
+				assert.Error(t, err)
+				assert.Equal(t, "", result)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.result, result)
+			}
+		})
+	}
+
+	assert.Error(t, e.SetBase(3))
+
+	outputs := []struct {
+		base      int
+		precision int32
+		expr      string
+		result    string
+	}{
+		{base: 16, precision: 16, expr: "255", result: "0xFF"},
+		{base: 16, precision: 16, expr: "0 - 255.5", result: "-0xFF.8"},
+		{base: 16, precision: 16, expr: "1 / 3", result: "0x0.5555555555555555"},
+		{base: 2, precision: 16, expr: "10", result: "0b1010"},
+		{base: 2, precision: 4, expr: "0.1", result: "0b0.001"},
+		{base: 2, precision: 4, expr: "0 - 0.01", result: "0b0"},
+		{base: 8, precision: 16, expr: "493", result: "0o755"},
+		{base: 10, precision: 16, expr: "0xFF", result: "255"},
+	}
+	for _, tc := range outputs {
+		require.NoError(t, e.SetBase(tc.base))
+
+		result, err := e.Execute(tc.expr, tc.precision)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.result, result)
+	}
+}
+
+func TestBitwise(t *testing.T) {
+	testcases := map[string]struct {
+		expr   string
+		result string
+		err    bool
+	}{
+		"and":             {expr: "0b1100 & 0b1010", result: "8", err: false},
+		"or":              {expr: "0b1100 | 0b1010", result: "14", err: false},
+		"xor":             {expr: "0b1100 xor 0b1010", result: "6", err: false},
+		"not":             {expr: "~5", result: "-6", err: false},
+		"left_shift":      {expr: "1 << 10", result: "1024", err: false},
+		"right_shift":     {expr: "1024 >> 3", result: "128", err: false},
+		"precedence":      {expr: "0xF0 | 0x0F xor 0xFF & 1 << 2", result: "251", err: false},
+		"shift_addition":  {expr: "1 << 2 + 1", result: "8", err: false},
+		"compare":         {expr: "6 & 3 == 2", result: "1", err: false},
+		"and_not_logical": {expr: "1 & 2", result: "0", err: false},
+		"non_integer":     {expr: "1.5 & 1", result: "", err: true},
+		"non_integer_not": {expr: "~0.5", result: "", err: true},
+		"negative_shift":  {expr: "1 << (0 - 1)", result: "", err: true},
+		"huge_shift":      {expr: "1 << 1e9", result: "", err: true},
+		"xor_word":        {expr: "xorx", result: "", err: true},
+	}
+	e := executor.NewExecutor(nil, nil)
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			result, err := e.Execute(tc.expr, 16)
+			if tc.err {
+				assert.Error(t, err)
+				assert.Equal(t, "", result)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.result, result)
+			}
+		})
+	}
+
+	_, err := e.Execute("3 | 1.5", 16)
+	assert.EqualError(t, err, "expression at [3]: apply operator `|`: operands must be integers")
+}
+
+func TestIntMode(t *testing.T) {
+	modes := map[string]executor.IntMode{
+		"off": {},
+		"i8":  {Bits: 8, Signed: true},
+		"u16": {Bits: 16, Signed: false},
+		"i64": {Bits: 64, Signed: true},
+	}
+	for text, expected := range modes {
+		mode, err := executor.ParseIntMode(text)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, mode)
+		assert.Equal(t, text, mode.String())
+	}
+	for _, text := range []string{"i7", "x8", "u", "i128"} {
+		_, err := executor.ParseIntMode(text)
+		assert.Error(t, err, text)
+	}
+
+	testcases := []struct {
+		mode   executor.IntMode
+		base   int
+		expr   string
+		result string
+	}{
+		{mode: executor.IntMode{Bits: 8, Signed: false}, base: 10, expr: "255 + 1", result: "0"},
+		{mode: executor.IntMode{Bits: 8, Signed: false}, base: 10, expr: "0 - 1", result: "255"},
+		{mode: executor.IntMode{Bits: 8, Signed: true}, base: 10, expr: "127 + 1", result: "-128"},
+		{mode: executor.IntMode{Bits: 8, Signed: true}, base: 16, expr: "0 - 1", result: "0xFF"},
+		{mode: executor.IntMode{Bits: 16, Signed: true}, base: 2, expr: "0 - 2", result: "0b1111111111111110"},
+		{mode: executor.IntMode{Bits: 32, Signed: false}, base: 16, expr: "~0", result: "0xFFFFFFFF"},
+		{mode: executor.IntMode{Bits: 32, Signed: true}, base: 10, expr: "7 / 2", result: "3"},
+		{mode: executor.IntMode{Bits: 32, Signed: true}, base: 10, expr: "0 - 7 / 2", result: "-3"},
+		{mode: executor.IntMode{Bits: 32, Signed: true}, base: 10, expr: "3 / 2", result: "1"},
+		{mode: executor.IntMode{Bits: 32, Signed: true}, base: 10, expr: "-7 / 2", result: "-3"},
+		{mode: executor.IntMode{Bits: 8, Signed: false}, base: 10, expr: "(7 / 2) * 2", result: "6"},
+		{mode: executor.IntMode{Bits: 8, Signed: false}, base: 10, expr: "1 << 8", result: "0"},
+		{mode: executor.IntMode{Bits: 8, Signed: true}, base: 10, expr: "0x80 >> 1", result: "-64"},
+		{mode: executor.IntMode{Bits: 64, Signed: false}, base: 16, expr: "0 - 1", result: "0xFFFFFFFFFFFFFFFF"},
+	}
+	for _, tc := range testcases {
+		e := executor.NewExecutor(nil, nil)
+		require.NoError(t, e.SetIntMode(tc.mode))
+		require.NoError(t, e.SetBase(tc.base))
+
+		result, err := e.Execute(tc.expr, 16)
+		assert.NoError(t, err, tc.expr)
+		assert.Equal(t, tc.result, result, tc.expr)
+	}
+
+	e := executor.NewExecutor(nil, nil)
+	assert.Error(t, e.SetIntMode(executor.IntMode{Bits: 12}))
+
+	require.NoError(t, e.SetIntMode(executor.IntMode{Bits: 8, Signed: false}))
+	result, err := e.Execute("(200 + 100) >> 1", 16)
+	assert.NoError(t, err)
+	assert.Equal(t, "22", result)
+
+	invalid := map[string]string{
+		"1.9":          "expression in rage [1, 3]: number must be an integer in integer mode",
+		"0.5 & 1":      "expression in rage [1, 3]: number must be an integer in integer mode",
+		"[1, 2.5]":     "expression in rage [5, 7]: number must be an integer in integer mode",
+		"sqrt(2) & 1":  "expression in rage [1, 7]: operand must be an integer",
+		"1 << (3 / 2)": "expression in rage [7, 11]: operand must be an integer",
+		"~(1 / 2)":     "expression in rage [3, 7]: operand must be an integer",
+		"Pi xor 1":     "expression in rage [1, 2]: operand must be an integer",
+		"2 | sqrt(2)":  "expression in rage [5, 11]: operand must be an integer",
+	}
+	for expr, expected := range invalid {
+		var exprErr *executor.ExprError
+		_, err = e.Execute(expr, 16)
+		require.ErrorAs(t, err, &exprErr, expr)
+		assert.EqualError(t, err, expected, expr)
+	}
+}
+
+func TestUnits(t *testing.T) {
+	testcases := map[string]struct {
+		expr   string
+		result string
+		err    bool
+	}{
+		"literal":            {expr: "5 km", result: "5 km", err: false},
+		"no_space":           {expr: "3h", result: "3 h", err: false},
+		"compound":           {expr: "12 kg*m/s^2", result: "12 kg*m/s^2", err: false},
+		"unit_power":         {expr: "4 m^2", result: "4 m^2", err: false},
+		"addition":           {expr: "5 km + 300 m", result: "5.3 km", err: false},
+		"subtraction":        {expr: "1 h - 30 min", result: "0.5 h", err: false},
+		"multiplication":     {expr: "2 m * 3 m", result: "6 m^2", err: false},
+		"scale":              {expr: "2 * 3 km", result: "6 km", err: false},
+		"division":           {expr: "100 km / 2 h", result: "50 km/h", err: false},
+		"inverse":            {expr: "1 / 2 s", result: "0.5 s^-1", err: false},
+		"unit_precedence":    {expr: "1/3 km", result: "0.3333333333333333 km^-1", err: false},
+		"parentheses":        {expr: "(1/3) km", result: "0.3333333333333333 km", err: false},
+		"parentheses_speed":  {expr: "(2 + 3) m/s", result: "5 m/s", err: false},
+		"unit_divide_number": {expr: "5 m / 2", result: "2.5 m", err: false},
+		"cancel":             {expr: "5 km / 1 m", result: "5000", err: false},
+		"power":              {expr: "(2 m)^3", result: "8 m^3", err: false},
+		"negative":           {expr: "-5 km", result: "-5 km", err: false},
+		"convert_to":         {expr: "60 mph to km/h", result: "96.56064 km/h", err: false},
+		"convert_in":         {expr: "1 inch in cm", result: "2.54 cm", err: false},
+		"convert_derived":    {expr: "3 kg*m/s^2 to N", result: "3 N", err: false},
+		"convert_prefixed":   {expr: "1 kWh to MJ", result: "3.6 MJ", err: false},
+		"convert_imperial":   {expr: "1 mi to ft", result: "5280 ft", err: false},
+		"convert_area":       {expr: "2 km^2 to m^2", result: "2000000 m^2", err: false},
+		"convert_precedence": {expr: "1 km + 500 m to m", result: "1500 m", err: false},
+		"compare":            {expr: "1 km > 500 m", result: "1", err: false},
+		"min":                {expr: "min(1 km, 500 m)", result: "500 m", err: false},
+		"abs":                {expr: "abs(-5 km)", result: "5 km", err: false},
+		"round":              {expr: "round(2.54 cm, 1)", result: "2.5 cm", err: false},
+		"minute_function":    {expr: "min(5, 2) min", result: "", err: true},
+		"incompatible":       {expr: "5 km + 3 h", result: "", err: true},
+		"add_number":         {expr: "5 km + 1", result: "", err: true},
+		"convert_mismatch":   {expr: "5 km to s", result: "", err: true},
+		"convert_not_unit":   {expr: "5 km to 2 m", result: "", err: true},
+		"dimensionless_arg":  {expr: "sqrt(4 m^2)", result: "", err: true},
+		"fraction_power":     {expr: "(4 m)^0.5", result: "", err: true},
+		"unit_exponent":      {expr: "2^(1 m)", result: "", err: true},
+		"unknown_unit":       {expr: "5 parsec", result: "", err: true},
+		"unit_alone":         {expr: "m", result: "", err: true},
+		"unit_operand":       {expr: "2 * m", result: "", err: true},
+	}
+	e := executor.NewExecutor(nil, nil)
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			result, err := e.Execute(tc.expr, 16)
+			if tc.err {
+				assert.Error(t, err)
+				assert.Equal(t, "", result)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.result, result)
+			}
+		})
+	}
+
+	_, err := e.Execute("5 km + 3 h", 16)
+	assert.EqualError(t, err, "expression at [6]: apply operator `+`: incompatible dimensions length and time")
+
+	_, err = e.Execute("5 km to s", 16)
+	assert.EqualError(t, err, "expression in rage [6, 7]: apply operator `to`: can't convert length to time")
+
+	result, err := e.Execute("d = 42 km", 16)
+	require.NoError(t, err)
+	assert.Equal(t, "42 km", result)
+
+	result, err = e.Execute("d / 2 h to m/s", 16)
+	require.NoError(t, err)
+	assert.Equal(t, "5.8333333333333333 m/s", result)
+
+	_, err = e.Execute("m", 16)
+	assert.EqualError(t, err, "expression at [1]: unknown identifier `m`")
+
+	_, err = e.Execute("m = 3", 16)
+	assert.EqualError(t, err, "expression at [1]: can't redefine unit `m`")
+
+	_, err = e.Execute("km = 3", 16)
+	assert.EqualError(t, err, "expression in rage [1, 2]: can't redefine unit `km`")
+
+	assert.EqualError(t, e.Env().SetVariable("h", executor.NewNumber(decimal.Zero)), "can't redefine unit `h`")
+
+	result, err = e.Execute("5 m", 16)
+	require.NoError(t, err)
+	assert.Equal(t, "5 m", result)
+}
+
+func TestExactMode(t *testing.T) {
+	for _, text := range []string{"off", "on", "mixed"} {
+		mode, err := executor.ParseExactMode(text)
+		assert.NoError(t, err)
+		assert.Equal(t, text, mode.String())
+	}
+	_, err := executor.ParseExactMode("yes")
+	assert.Error(t, err)
+
+	testcases := map[string]struct {
+		mode   executor.ExactMode
+		expr   string
+		result string
+	}{
+		"off":              {mode: executor.ExactOff, expr: "1/3*3 == 1", result: "0"},
+		"exact":            {mode: executor.ExactFraction, expr: "1/3*3 == 1", result: "1"},
+		"fraction":         {mode: executor.ExactFraction, expr: "4/3", result: "4/3"},
+		"negative":         {mode: executor.ExactFraction, expr: "-4/3", result: "-4/3"},
+		"decimal_literals": {mode: executor.ExactFraction, expr: "0.1 + 0.2", result: "3/10"},
+		"power":            {mode: executor.ExactFraction, expr: "(2/3)^3", result: "8/27"},
+		"negative_power":   {mode: executor.ExactFraction, expr: "2^(0-3)", result: "1/8"},
+		"large_power":      {mode: executor.ExactFraction, expr: "2^100 / 2^99", result: "2"},
+		"compare":          {mode: executor.ExactFraction, expr: "1/3 < 1/2", result: "1"},
+		"floor":            {mode: executor.ExactFraction, expr: "floor(-7/2)", result: "-4"},
+		"round":            {mode: executor.ExactFraction, expr: "round(5/2)", result: "3"},
+		"abs":              {mode: executor.ExactFraction, expr: "abs(-1/3)", result: "1/3"},
+		"min":              {mode: executor.ExactFraction, expr: "min(1/3, 1/4)", result: "1/4"},
+		"fallback":         {mode: executor.ExactFraction, expr: "sqrt(4) / 3", result: "0.6666666666666667"},
+		"fallback_power":   {mode: executor.ExactFraction, expr: "4^(1/2)", result: "2"},
+		"mixed":            {mode: executor.ExactMixed, expr: "4/3", result: "1 1/3"},
+		"mixed_negative":   {mode: executor.ExactMixed, expr: "-7/2", result: "-3 1/2"},
+		"mixed_proper":     {mode: executor.ExactMixed, expr: "2/3", result: "2/3"},
+		"mixed_integer":    {mode: executor.ExactMixed, expr: "6/3", result: "2"},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			e := executor.NewExecutor(nil, nil)
+			require.NoError(t, e.SetExactMode(tc.mode))
+
+			result, err := e.Execute(tc.expr, 16)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.result, result)
+		})
+	}
+
+	e := executor.NewExecutor(nil, nil)
+	require.NoError(t, e.SetExactMode(executor.ExactFraction))
+
+	_, err = e.Execute("x = 1/3", 16)
+	require.NoError(t, err)
+	x, ok := e.Env().Variable("x")
+	require.True(t, ok)
+	assert.IsType(t, executor.Rational{}, x)
+	assert.Equal(t, "1/3", x.String())
+
+	require.NoError(t, e.SetExactMode(executor.ExactOff))
+	result, err := e.Execute("x * 3 + 0.5", 16)
+	require.NoError(t, err)
+	assert.Equal(t, "1.5", result)
+
+	require.NoError(t, e.SetBase(16))
+	require.NoError(t, e.SetExactMode(executor.ExactFraction))
+	result, err = e.Execute("255/16", 16)
+	require.NoError(t, err)
+	assert.Equal(t, "0xFF/0x10", result)
+
+	assert.Error(t, e.SetExactMode(executor.ExactMode(10)))
+}
+
+func TestAngleMode(t *testing.T) {
+	for _, text := range []string{"rad", "deg", "grad"} {
+		mode, err := executor.ParseAngleMode(text)
+		assert.NoError(t, err)
+		assert.Equal(t, text, mode.String())
+	}
+	_, err := executor.ParseAngleMode("turn")
+	assert.Error(t, err)
+
+	testcases := map[string]struct {
+		mode   executor.AngleMode
+		expr   string
+		result string
+		err    bool
+	}{
+		"radians":          {mode: executor.AngleRadians, expr: "sin(Pi/6)", result: "0.5", err: false},
+		"degrees":          {mode: executor.AngleDegrees, expr: "sin(30)", result: "0.5", err: false},
+		"gradians":         {mode: executor.AngleGradians, expr: "cos(200)", result: "-1", err: false},
+		"degrees_exact":    {mode: executor.AngleDegrees, expr: "cos(90)", result: "0", err: false},
+		"degrees_negative": {mode: executor.AngleDegrees, expr: "sin(-450)", result: "-1", err: false},
+		"degrees_reduced":  {mode: executor.AngleDegrees, expr: "tan(405)", result: "1", err: false},
+		"degrees_tan":      {mode: executor.AngleDegrees, expr: "tan(90)", result: "", err: true},
+		"asin":             {mode: executor.AngleDegrees, expr: "asin(0.5)", result: "30", err: false},
+		"acos":             {mode: executor.AngleGradians, expr: "acos(0)", result: "100", err: false},
+		"atan":             {mode: executor.AngleDegrees, expr: "atan(1)", result: "45", err: false},
+		"atan2":            {mode: executor.AngleDegrees, expr: "atan2(-1, -1)", result: "-135", err: false},
+		"atan_radians":     {mode: executor.AngleRadians, expr: "atan(1) * 4", result: "3.1415926535897932", err: false},
+		"unit_degrees":     {mode: executor.AngleRadians, expr: "sin(30 deg)", result: "0.5", err: false},
+		"unit_sign":        {mode: executor.AngleRadians, expr: "sin(30°)", result: "0.5", err: false},
+		"unit_gradians":    {mode: executor.AngleRadians, expr: "cos(100grad)", result: "0", err: false},
+		"unit_radians":     {mode: executor.AngleDegrees, expr: "sin(3.1415926535897932 rad)", result: "0", err: false},
+		"unit_prefix":      {mode: executor.AngleDegrees, expr: "cos(0 mrad)", result: "1", err: false},
+		"unit_sum":         {mode: executor.AngleRadians, expr: "sin(20° + 10 deg)", result: "0.5", err: false},
+		"conversion":       {mode: executor.AngleRadians, expr: "90 deg to grad", result: "100 grad", err: false},
+		"conversion_rad":   {mode: executor.AngleRadians, expr: "180° to rad", result: "3.1415926535897932 rad", err: false},
+		"degree_sign":      {mode: executor.AngleRadians, expr: "15° * 2", result: "30°", err: false},
+		"not_angle":        {mode: executor.AngleDegrees, expr: "sin(5 m)", result: "", err: true},
+		"function":         {mode: executor.AngleDegrees, expr: "rad(180)", result: "3.1415926535897932", err: false},
+		"arg":              {mode: executor.AngleDegrees, expr: "arg(i)", result: "90", err: false},
+		"arg_complex":      {mode: executor.AngleDegrees, expr: "arg(-1 - i)", result: "-135", err: false},
+		"arg_real":         {mode: executor.AngleGradians, expr: "arg(-2)", result: "200", err: false},
+		"arg_radians":      {mode: executor.AngleRadians, expr: "arg(i)", result: "1.5707963267948966", err: false},
+		"rect":             {mode: executor.AngleDegrees, expr: "rect(2, 90)", result: "2i", err: false},
+		"rect_degrees":     {mode: executor.AngleDegrees, expr: "rect(2, 30)", result: "1.7320508075688773+i", err: false},
+		"rect_unit":        {mode: executor.AngleRadians, expr: "rect(1, 180 deg)", result: "-1", err: false},
+		"rect_not_angle":   {mode: executor.AngleDegrees, expr: "rect(1, 5 m)", result: "", err: true},
+		"polar":            {mode: executor.AngleDegrees, expr: "polar(1 + i)", result: "[1.414213562373095, 45]", err: false},
+		"polar_real":       {mode: executor.AngleGradians, expr: "polar(-2)", result: "[2, 200]", err: false},
+		"polar_radians":    {mode: executor.AngleRadians, expr: "polar(3i)", result: "[3, 1.5707963267948966]", err: false},
+		"polar_rect":       {mode: executor.AngleDegrees, expr: "polar(rect(2, -60))", result: "[2, -60]", err: false},
+		"polar_not_number": {mode: executor.AngleDegrees, expr: "polar(5 m)", result: "", err: true},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			e := executor.NewExecutor(nil, nil)
+			require.NoError(t, e.SetAngleMode(tc.mode))
+
+			result, err := e.Execute(tc.expr, 16)
+			if tc.err {
+				assert.Error(t, err)
+				assert.Equal(t, "", result)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.result, result)
+			}
+		})
+	}
+
+	e := executor.NewExecutor(nil, nil)
+	require.NoError(t, e.SetAngleMode(executor.AngleDegrees))
+	_, err = e.Execute("f(x) = sin(x)", 16)
+	require.NoError(t, err)
+	result, err := e.Execute("f(30)", 16)
+	require.NoError(t, err)
+	assert.Equal(t, "0.5", result)
+
+	program, err := e.Compile("sin(90)")
+	require.NoError(t, err)
+	require.NoError(t, e.SetAngleMode(executor.AngleRadians))
+	value, err := program.Run(e.Env())
+	require.NoError(t, err)
+	assert.Equal(t, "1", value.String())
+
+	assert.Equal(t, executor.AngleRadians, e.AngleMode())
+	assert.Error(t, e.SetAngleMode(executor.AngleMode(10)))
+}
+
+func TestComplex(t *testing.T) {
+	testcases := map[string]struct {
+		complex bool
+		expr    string
+		result  string
+		err     bool
+	}{
+		"imaginary":        {complex: false, expr: "i", result: "i", err: false},
+		"square":           {complex: false, expr: "i^2", result: "-1", err: false},
+		"suffix":           {complex: false, expr: "2i", result: "2i", err: false},
+		"rectangular":      {complex: false, expr: "1+2i", result: "1+2i", err: false},
+		"negative_imag":    {complex: false, expr: "1-2i", result: "1-2i", err: false},
+		"negative_unit":    {complex: false, expr: "-i", result: "-i", err: false},
+		"multiplication":   {complex: false, expr: "(1+2i)*(3-i)", result: "5+5i", err: false},
+		"division":         {complex: false, expr: "(1+2i)/(3-4i)", result: "-0.2+0.4i", err: false},
+		"real_result":      {complex: false, expr: "(1+i)*(1-i)", result: "2", err: false},
+		"abs":              {complex: false, expr: "abs(3+4i)", result: "5", err: false},
+		"arg":              {complex: false, expr: "arg(-2i)", result: "-1.5707963267948966", err: false},
+		"arg_real":         {complex: false, expr: "arg(-1)", result: "3.1415926535897932", err: false},
+		"re":               {complex: false, expr: "re(1+2i)", result: "1", err: false},
+		"im":               {complex: false, expr: "im(1+2i)", result: "2", err: false},
+		"im_real":          {complex: false, expr: "im(5)", result: "0", err: false},
+		"conj":             {complex: false, expr: "conj(1+2i)", result: "1-2i", err: false},
+		"rect":             {complex: false, expr: "rect(2, Pi/2)", result: "2i", err: false},
+		"polar_roundtrip":  {complex: false, expr: "rect(abs(1+i), arg(1+i))", result: "1+i", err: false},
+		"euler":            {complex: false, expr: "e^(i*Pi)", result: "-1", err: false},
+		"imaginary_power":  {complex: false, expr: "i^i", result: "0.2078795763507619", err: false},
+		"complex_sqrt":     {complex: false, expr: "sqrt(2i)", result: "1+i", err: false},
+		"equal":            {complex: false, expr: "2i == i*2", result: "1", err: false},
+		"not_equal":        {complex: false, expr: "i != 1", result: "1", err: false},
+		"real_sqrt":        {complex: false, expr: "sqrt(-4)", result: "", err: true},
+		"real_power":       {complex: false, expr: "(-8)^(1/3)", result: "", err: true},
+		"compare":          {complex: false, expr: "i < 1", result: "", err: true},
+		"quantity":         {complex: false, expr: "5 km * i", result: "", err: true},
+		"convert":          {complex: false, expr: "i to m", result: "", err: true},
+		"complex_sqrt_neg": {complex: true, expr: "sqrt(-4)", result: "2i", err: false},
+		"complex_sqrt_pos": {complex: true, expr: "sqrt(4)", result: "2", err: false},
+		"complex_power":    {complex: true, expr: "(-8)^(1/3)", result: "1+1.7320508075688773i", err: false},
+		"complex_half":     {complex: true, expr: "(-1)^0.5", result: "i", err: false},
+		"complex_units":    {complex: true, expr: "5 km * 2", result: "10 km", err: false},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			e := executor.NewExecutor(nil, nil)
+			e.SetComplexMode(tc.complex)
+
+			result, err := e.Execute(tc.expr, 16)
+			if tc.err {
+				assert.Error(t, err)
+				assert.Equal(t, "", result)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.result, result)
+			}
+		})
+	}
+
+	e := executor.NewExecutor(nil, nil)
+	_, err := e.Execute("z = 3-4i", 16)
+	require.NoError(t, err)
+	z, ok := e.Env().Variable("z")
+	require.True(t, ok)
+	require.IsType(t, executor.Complex{}, z)
+	assert.Equal(t, "3", z.(executor.Complex).Real().String())
+	assert.Equal(t, "-4", z.(executor.Complex).Imag().String())
+
+	require.NoError(t, e.SetBase(16))
+	result, err := e.Execute("15+2i", 16)
+	require.NoError(t, err)
+	assert.Equal(t, "0xF+0x2i", result)
+
+	_, err = e.Execute("i = 2", 16)
+	require.NoError(t, err)
+	result, err = e.Execute("i * 3", 16)
+	require.NoError(t, err)
+	assert.Equal(t, "0x6", result)
+
+	result, err = e.Execute("3i", 16)
+	require.NoError(t, err)
+	assert.Equal(t, "0x6", result, "variable shadows imaginary unit")
+}
+
+func TestLists(t *testing.T) {
+	testcases := map[string]struct {
+		expr   string
+		result string
+		err    bool
+	}{
+		"literal":          {expr: "[1, 2, 3]", result: "[1, 2, 3]", err: false},
+		"empty":            {expr: "[]", result: "[]", err: false},
+		"nested":           {expr: "[[1, 2], [3]]", result: "[[1, 2], [3]]", err: false},
+		"expressions":      {expr: "[1 + 1, -2, 5 km]", result: "[2, -2, 5 km]", err: false},
+		"range":            {expr: "1..5", result: "[1, 2, 3, 4, 5]", err: false},
+		"range_reverse":    {expr: "3..1", result: "[3, 2, 1]", err: false},
+		"range_precedence": {expr: "1..1+2", result: "[1, 2, 3]", err: false},
+		"add":              {expr: "[1, 2] + [3, 4]", result: "[4, 6]", err: false},
+		"scalar":           {expr: "[1, 2] * 3", result: "[3, 6]", err: false},
+		"scalar_left":      {expr: "2 ^ [1, 2, 3]", result: "[2, 4, 8]", err: false},
+		"negate":           {expr: "-[1, 2]", result: "[-1, -2]", err: false},
+		"modulo":           {expr: "7 % [2, 3]", result: "[1, 1]", err: false},
+		"bitwise":          {expr: "[1, 2] | 4", result: "[5, 6]", err: false},
+		"nested_add":       {expr: "[[1, 2], [3, 4]] + 1", result: "[[2, 3], [4, 5]]", err: false},
+		"units":            {expr: "[1 km, 500 m] to m", result: "[1000 m, 500 m]", err: false},
+		"index":            {expr: "[1, 2, 3][0]", result: "1", err: false},
+		"index_negative":   {expr: "[1, 2, 3][-1]", result: "3", err: false},
+		"index_range":      {expr: "(1..10)[3]", result: "4", err: false},
+		"index_nested":     {expr: "[[1, 2], [3, 4]][1][0]", result: "3", err: false},
+		"equal":            {expr: "[1, 2] == [1, 2]", result: "1", err: false},
+		"not_equal":        {expr: "[1, 2] != [1]", result: "1", err: false},
+		"sum":              {expr: "sum(1..100)", result: "5050", err: false},
+		"sum_empty":        {expr: "sum([])", result: "0", err: false},
+		"sum_units":        {expr: "sum([1 km, 500 m])", result: "1.5 km", err: false},
+		"sum_nested":       {expr: "sum([[1, 2], [3, 4]])", result: "[4, 6]", err: false},
+		"mean":             {expr: "mean([1, 2, 3, 4])", result: "2.5", err: false},
+		"median_odd":       {expr: "median([3, 1, 2])", result: "2", err: false},
+		"median_even":      {expr: "median([4, 1, 3, 2])", result: "2.5", err: false},
+		"stddev":           {expr: "stddev([2, 4, 4, 4, 5, 5, 7, 9])", result: "2.1380899352993951", err: false},
+		"stddev_units":     {expr: "stddev([1 m, 2 m, 3 m])", result: "1 m", err: false},
+		"len":              {expr: "len(1..10)", result: "10", err: false},
+		"min":              {expr: "min([3, 1, 2])", result: "1", err: false},
+		"max":              {expr: "max([3, 1, 2])", result: "3", err: false},
+		"length_mismatch":  {expr: "[1, 2] + [1, 2, 3]", result: "", err: true},
+		"compare":          {expr: "[1, 2] < [1, 3]", result: "", err: true},
+		"out_of_range":     {expr: "[1, 2, 3][3]", result: "", err: true},
+		"fraction_index":   {expr: "[1, 2, 3][1.5]", result: "", err: true},
+		"index_number":     {expr: "5[0]", result: "", err: true},
+		"missing_index":    {expr: "[1, 2][]", result: "", err: true},
+		"unclosed":         {expr: "[1, 2", result: "", err: true},
+		"trailing_comma":   {expr: "[1, ]", result: "", err: true},
+		"fraction_range":   {expr: "1.5..3", result: "", err: true},
+		"huge_range":       {expr: "1..10^9", result: "", err: true},
+		"not_list":         {expr: "len(5)", result: "", err: true},
+		"mean_empty":       {expr: "mean([])", result: "", err: true},
+		"stddev_single":    {expr: "stddev([1])", result: "", err: true},
+		"condition":        {expr: "[1] && 1", result: "", err: true},
+	}
+	e := executor.NewExecutor(nil, nil)
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			result, err := e.Execute(tc.expr, 16)
+			if tc.err {
+				assert.Error(t, err)
+				assert.Equal(t, "", result)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.result, result)
+			}
+		})
+	}
+
+	_, err := e.Execute("[1, 2, 3][3]", 16)
+	assert.EqualError(t, err, "expression in rage [10, 12]: index: index 3 out of range for list of length 3")
+
+	result, err := e.Execute("x = 1..3", 16)
+	require.NoError(t, err)
+	assert.Equal(t, "[1, 2, 3]", result)
+
+	x, ok := e.Env().Variable("x")
+	require.True(t, ok)
+	require.IsType(t, executor.List{}, x)
+	assert.Equal(t, 3, x.(executor.List).Len())
+
+	result, err = e.Execute("x[-1] * 2", 16)
+	require.NoError(t, err)
+	assert.Equal(t, "6", result)
+
+	require.NoError(t, e.SetExactMode(executor.ExactFraction))
+	result, err = e.Execute("[1, 2] / 3", 16)
+	require.NoError(t, err)
+	assert.Equal(t, "[1/3, 2/3]", result)
+
+	require.NoError(t, e.SetExactMode(executor.ExactOff))
+	require.NoError(t, e.SetIntMode(executor.IntMode{Bits: 8, Signed: true}))
+	require.NoError(t, e.SetBase(16))
+	result, err = e.Execute("[1, 2] - 3", 16)
+	require.NoError(t, err)
+	assert.Equal(t, "[0xFE, 0xFF]", result)
+}
+
+func TestMatrices(t *testing.T) {
+	testcases := map[string]struct {
+		expr   string
+		result string
+		err    bool
+	}{
+		"multiply":          {expr: "[[1, 2], [3, 4]] * [[5, 6], [7, 8]]", result: "[[19, 22], [43, 50]]", err: false},
+		"multiply_rect":     {expr: "[[1, 2, 3]] * [[1], [2], [3]]", result: "[[14]]", err: false},
+		"column_vector":     {expr: "[[1, 2], [3, 4]] * [1, 1]", result: "[3, 7]", err: false},
+		"row_vector":        {expr: "[1, 1] * [[1, 2], [3, 4]]", result: "[4, 6]", err: false},
+		"scalar":            {expr: "[[1, 2], [3, 4]] * 2", result: "[[2, 4], [6, 8]]", err: false},
+		"add":               {expr: "[[1, 2], [3, 4]] + [[1, 1], [1, 1]]", result: "[[2, 3], [4, 5]]", err: false},
+		"vectors":           {expr: "[1, 2] * [3, 4]", result: "[3, 8]", err: false},
+		"complex":           {expr: "[[i, 0], [0, i]] * [[i, 0], [0, i]]", result: "[[-1, 0], [0, -1]]", err: false},
+		"transpose":         {expr: "transpose([[1, 2, 3], [4, 5, 6]])", result: "[[1, 4], [2, 5], [3, 6]]", err: false},
+		"det":               {expr: "det([[1, 2], [3, 4]])", result: "-2", err: false},
+		"det_3x3":           {expr: "det([[2, 0, 1], [1, 3, 2], [1, 1, 2]])", result: "6", err: false},
+		"det_swap":          {expr: "det([[0, 1], [1, 0]])", result: "-1", err: false},
+		"det_singular":      {expr: "det([[1, 2], [2, 4]])", result: "0", err: false},
+		"inverse":           {expr: "inverse([[4, 7], [2, 6]])", result: "[[0.6, -0.7], [-0.2, 0.4]]", err: false},
+		"inverse_product":   {expr: "[[1, 2], [3, 4]] * inverse([[1, 2], [3, 4]])", result: "[[1, 0], [0, 1]]", err: false},
+		"identity":          {expr: "identity(2)", result: "[[1, 0], [0, 1]]", err: false},
+		"solve":             {expr: "solve([[2, 1], [1, 3]], [3, 5])", result: "[0.8, 1.4]", err: false},
+		"solve_matrix":      {expr: "solve([[1, 2], [3, 4]], [[5], [6]])", result: "[[-4], [4.5]]", err: false},
+		"solve_3x3":         {expr: "solve([[1, 1, 1], [0, 2, 5], [2, 5, -1]], [6, -4, 27])", result: "[5, 3, -2]", err: false},
+		"multiply_mismatch": {expr: "[[1, 2, 3], [4, 5, 6]] * [[1, 2], [3, 4]]", result: "", err: true},
+		"vector_mismatch":   {expr: "[[1, 2], [3, 4]] * [1, 2, 3]", result: "", err: true},
+		"ragged":            {expr: "det([[1, 2], [3]])", result: "", err: true},
+		"not_square":        {expr: "det([[1, 2, 3], [4, 5, 6]])", result: "", err: true},
+		"singular":          {expr: "inverse([[1, 2], [2, 4]])", result: "", err: true},
+		"solve_mismatch":    {expr: "solve([[1, 2], [3, 4]], [1, 2, 3])", result: "", err: true},
+		"solve_singular":    {expr: "solve([[1, 2], [2, 4]], [1, 2])", result: "", err: true},
+		"identity_zero":     {expr: "identity(0)", result: "", err: true},
+		"transpose_vector":  {expr: "transpose([1, 2])", result: "", err: true},
+		"power":             {expr: "[[1, 1], [1, 0]] ^ 10", result: "[[89, 55], [55, 34]]", err: false},
+		"power_square":      {expr: "[[1, 2], [3, 4]]²", result: "[[7, 10], [15, 22]]", err: false},
+		"power_zero":        {expr: "[[1, 2], [3, 4]] ^ 0", result: "[[1, 0], [0, 1]]", err: false},
+		"power_negative":    {expr: "[[4, 7], [2, 6]] ^ (-1)", result: "[[0.6, -0.7], [-0.2, 0.4]]", err: false},
+		"power_vector":      {expr: "[1, 2] ^ 2", result: "[1, 4]", err: false},
+		"divide":            {expr: "[[2, 4], [6, 8]] / 2", result: "[[1, 2], [3, 4]]", err: false},
+		"power_fraction":    {expr: "[[1, 2], [3, 4]] ^ 0.5", result: "", err: true},
+		"power_not_square":  {expr: "[[1, 2, 3], [4, 5, 6]] ^ 2", result: "", err: true},
+		"power_singular":    {expr: "[[1, 2], [2, 4]] ^ (-1)", result: "", err: true},
+		"power_list":        {expr: "[[1, 2], [3, 4]] ^ [1, 2]", result: "", err: true},
+	}
+	e := executor.NewExecutor(nil, nil)
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			result, err := e.Execute(tc.expr, 16)
+			if tc.err {
+				assert.Error(t, err)
+				assert.Equal(t, "", result)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.result, result)
+			}
+		})
+	}
+
+	_, err := e.Execute("[[1, 2, 3], [4, 5, 6]] * [[1, 2], [3, 4]]", 16)
+	var exprErr *executor.ExprError
+	require.ErrorAs(t, err, &exprErr)
+	assert.Equal(t, executor.Location{Start: 23, End: 24, StartColumn: 23, EndColumn: 24}, exprErr.Loc)
+	assert.EqualError(t, err, "expression at [24]: apply operator `*`: can't multiply 2x3 matrix by 2x2 matrix")
+
+	_, err = e.Execute("1 + det([[1, 2, 3], [4, 5, 6]])", 16)
+	require.ErrorAs(t, err, &exprErr)
+	assert.Equal(t, executor.Location{Start: 4, End: 7, StartColumn: 4, EndColumn: 7}, exprErr.Loc)
+
+	require.NoError(t, e.SetExactMode(executor.ExactFraction))
+	result, err := e.Execute("inverse([[1, 2], [3, 4]])", 16)
+	require.NoError(t, err)
+	assert.Equal(t, "[[-2, 1], [3/2, -1/2]]", result)
+
+	result, err = e.Execute("solve([[3, 1], [1, 2]], [1, 1])", 16)
+	require.NoError(t, err)
+	assert.Equal(t, "[1/5, 2/5]", result)
+}
+
+func TestNumberTheory(t *testing.T) {
+	testcases := map[string]struct {
+		expr   string
+		result string
+		err    bool
+	}{
+		"lcm":                {expr: "lcm(4, 6, 10)", result: "60", err: false},
+		"lcm_list":           {expr: "lcm([3, -4])", result: "12", err: false},
+		"lcm_zero":           {expr: "lcm(0, 3)", result: "0", err: false},
+		"factorial":          {expr: "factorial(5)", result: "120", err: false},
+		"factorial_zero":     {expr: "factorial(0)", result: "1", err: false},
+		"postfix":            {expr: "5!", result: "120", err: false},
+		"postfix_large":      {expr: "25!", result: "15511210043330985984000000", err: false},
+		"postfix_minus":      {expr: "0 - 3!", result: "-6", err: false},
+		"postfix_power":      {expr: "2^3!", result: "64", err: false},
+		"postfix_base":       {expr: "3!^2", result: "36", err: false},
+		"postfix_twice":      {expr: "3!!", result: "720", err: false},
+		"postfix_list":       {expr: "[1, 2, 3]!", result: "[1, 2, 6]", err: false},
+		"not_equal":          {expr: "5!=3", result: "1", err: false},
+		"ncr":                {expr: "nCr(52, 5)", result: "2598960", err: false},
+		"ncr_large":          {expr: "nCr(10^20, 2)", result: "4999999999999999999950000000000000000000", err: false},
+		"ncr_more":           {expr: "nCr(3, 5)", result: "0", err: false},
+		"npr":                {expr: "nPr(10, 3)", result: "720", err: false},
+		"is_prime":           {expr: "isPrime(97)", result: "1", err: false},
+		"is_prime_large":     {expr: "isPrime(2^61 - 1)", result: "1", err: false},
+		"not_prime":          {expr: "isPrime(91)", result: "0", err: false},
+		"is_prime_one":       {expr: "isPrime(1)", result: "0", err: false},
+		"next_prime":         {expr: "nextPrime(100)", result: "101", err: false},
+		"next_prime_small":   {expr: "nextPrime(-5)", result: "2", err: false},
+		"factor":             {expr: "factor(360)", result: "[2, 2, 2, 3, 3, 5]", err: false},
+		"factor_negative":    {expr: "factor(-12)", result: "[-1, 2, 2, 3]", err: false},
+		"factor_one":         {expr: "factor(1)", result: "[]", err: false},
+		"factor_large":       {expr: "factor(2^64 + 1)", result: "[274177, 67280421310721]", err: false},
+		"modpow":             {expr: "modpow(2, 100, 1000000007)", result: "976371285", err: false},
+		"modpow_negative":    {expr: "modpow(3, -1, 7)", result: "5", err: false},
+		"modinv":             {expr: "modinv(3, 7)", result: "5", err: false},
+		"modinv_negative":    {expr: "modinv(-3, 7)", result: "2", err: false},
+		"fib":                {expr: "fib(100)", result: "354224848179261915075", err: false},
+		"fib_zero":           {expr: "fib(0)", result: "0", err: false},
+		"fib_negative":       {expr: "fib(-6)", result: "-8", err: false},
+		"factorial_frac":     {expr: "factorial(1.5)", result: "", err: true},
+		"factorial_neg":      {expr: "(-1)!", result: "", err: true},
+		"factorial_large":    {expr: "100000!", result: "", err: true},
+		"factorial_unit":     {expr: "factorial(5 m)", result: "", err: true},
+		"ncr_negative":       {expr: "nCr(-1, 2)", result: "", err: true},
+		"factor_zero":        {expr: "factor(0)", result: "", err: true},
+		"modpow_zero":        {expr: "modpow(2, 3, 0)", result: "", err: true},
+		"modinv_none":        {expr: "modinv(2, 4)", result: "", err: true},
+		"postfix_operand":    {expr: "5!3", result: "", err: true},
+		"lcm_fraction":       {expr: "lcm(1.5, 3)", result: "", err: true},
+		"modpow_not_coprime": {expr: "modpow(2, -1, 4)", result: "", err: true},
+	}
+	e := executor.NewExecutor(nil, nil)
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			result, err := e.Execute(tc.expr, 16)
+			if tc.err {
+				assert.Error(t, err)
+				assert.Equal(t, "", result)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.result, result)
+			}
+		})
+	}
+
+	result, err := e.Execute("100!", 16)
+	require.NoError(t, err)
+	assert.Equal(t, "9332621544394415268169923885626670049071596826438162146859296389521759999322991560894146397615651828"+
+		"6253697920827223758251185210916864000000000000000000000000", result)
+
+	_, err = e.Execute("1 + nCr(5, 1.5)", 16)
+	var exprErr *executor.ExprError
+	require.ErrorAs(t, err, &exprErr)
+	assert.Equal(t, executor.Location{Start: 4, End: 7, StartColumn: 4, EndColumn: 7}, exprErr.Loc)
+	assert.EqualError(t, err, "expression in rage [5, 7]: apply function `nCr`: the second argument must be an integer")
+
+	require.NoError(t, e.SetExactMode(executor.ExactFraction))
+	result, err = e.Execute("5! / 7", 16)
+	require.NoError(t, err)
+	assert.Equal(t, "120/7", result)
+}
+
+func TestPercent(t *testing.T) {
+	testcases := map[string]struct {
+		expr   string
+		result string
+		err    bool
+	}{
+		"percent":        {expr: "15%", result: "0.15", err: false},
+		"add":            {expr: "200 + 15%", result: "230", err: false},
+		"sub":            {expr: "200 - 15%", result: "170", err: false},
+		"of":             {expr: "15% of 200", result: "30", err: false},
+		"multiply":       {expr: "200 * 15%", result: "30", err: false},
+		"parentheses":    {expr: "200 + (15%)", result: "230", err: false},
+		"add_of":         {expr: "200 + 15% of 50", result: "207.5", err: false},
+		"add_expression": {expr: "100 + 5% * 2", result: "100.1", err: false},
+		"units":          {expr: "50 km + 10%", result: "55 km", err: false},
+		"list":           {expr: "[100, 200] + 10%", result: "[110, 220]", err: false},
+		"modulo":         {expr: "11 % 3", result: "2", err: false},
+		"modulo_spaces":  {expr: "11%3", result: "2", err: false},
+		"modulo_group":   {expr: "5 % (2)", result: "1", err: false},
+		"pctchange":      {expr: "pctchange(50, 75)", result: "50", err: false},
+		"pctchange_down": {expr: "pctchange(200, 150)", result: "-25", err: false},
+		"pctchange_unit": {expr: "pctchange(2 km, 2500 m)", result: "25", err: false},
+		"of_number":      {expr: "2 of 3", result: "", err: true},
+		"of_missing":     {expr: "15% of", result: "", err: true},
+		"pctchange_zero": {expr: "pctchange(0, 5)", result: "", err: true},
+		"pctchange_dim":  {expr: "pctchange(2 km, 5 s)", result: "", err: true},
+	}
+	e := executor.NewExecutor(nil, nil)
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			result, err := e.Execute(tc.expr, 16)
+			if tc.err {
+				assert.Error(t, err)
+				assert.Equal(t, "", result)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.result, result)
+			}
+		})
+	}
+
+	_, err := e.Execute("2 of 3", 16)
+	assert.EqualError(t, err, "expression in rage [3, 4]: expected percent before `of`")
+
+	require.NoError(t, e.SetExactMode(executor.ExactFraction))
+	result, err := e.Execute("1/3 + 10%", 16)
+	require.NoError(t, err)
+	assert.Equal(t, "11/30", result)
+
+	_, err = e.Execute("5 % 0", 16)
+	assert.EqualError(t, err, "expression at [3]: apply operator `%`: division by zero")
+
+	_, err = e.Execute("[1, 2] % 0", 16)
+	assert.EqualError(t, err, "expression at [8]: apply operator `%`: division by zero")
+
+	require.NoError(t, e.SetExactMode(executor.ExactOff))
+	require.NoError(t, e.SetIntMode(executor.IntMode{Bits: 8}))
+	_, err = e.Execute("5 % (1 - 1)", 16)
+	assert.EqualError(t, err, "expression at [3]: apply operator `%`: division by zero")
+}
+
+func TestImplicitMultiplication(t *testing.T) {
+	e := executor.NewExecutor(nil, nil)
+	_, err := e.Execute("x = 3", 16)
+	require.NoError(t, err)
+
+	testcases := map[string]struct {
+		expr   string
+		result string
+		err    bool
+	}{
+		"constant":      {expr: "2Pi", result: "6.2831853071795865", err: false},
+		"variable":      {expr: "2x", result: "6", err: false},
+		"parentheses":   {expr: "3(4 + 1)", result: "15", err: false},
+		"groups":        {expr: "(1 + 2)(3 + 4)", result: "21", err: false},
+		"chain":         {expr: "2(3)(4)", result: "24", err: false},
+		"call":          {expr: "2sqrt(16)", result: "8", err: false},
+		"spaces":        {expr: "2 x", result: "6", err: false},
+		"division":      {expr: "1/2x", result: "1.5", err: false},
+		"power":         {expr: "2x^2", result: "18", err: false},
+		"power_left":    {expr: "2^3x", result: "24", err: false},
+		"sum":           {expr: "1 + 2x", result: "7", err: false},
+		"numbers":       {expr: "2 3", result: "", err: true},
+		"variables":     {expr: "x x", result: "", err: true},
+		"variable_call": {expr: "x(2)", result: "", err: true},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			result, err := e.Execute(tc.expr, 16)
+			if tc.err {
+				assert.Error(t, err)
+				assert.Equal(t, "", result)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.result, result)
+			}
+		})
+	}
+
+	e.SetStrictMode(true)
+	assert.True(t, e.StrictMode())
+
+	for _, expr := range []string{"2Pi", "3(4 + 1)", "(1 + 2)(3 + 4)"} {
+		_, err = e.Execute(expr, 16)
+		assert.Error(t, err, expr)
+	}
+
+	result, err := e.Execute("2 km", 16)
+	require.NoError(t, err)
+	assert.Equal(t, "2 km", result)
+}
+
+func TestIdentifierLexing(t *testing.T) {
+	e := executor.NewExecutor(nil, nil)
+	_, err := e.Execute("value = 3", 16)
+	require.NoError(t, err)
+
+	testcases := map[string]struct {
+		expr string
+		err  string
+	}{
+		"constant":     {expr: "Pie", err: "expression in rage [1, 3]: unknown identifier `Pie`, did you mean `Pi`?"},
+		"function":     {expr: "sinx", err: "expression in rage [1, 4]: unknown identifier `sinx`, did you mean `sin`?"},
+		"call":         {expr: "sqr(4)", err: "expression in rage [1, 3]: unknown function `sqr/1`, did you mean `sqrt`?"},
+		"variable":     {expr: "1 + valeu", err: "expression in rage [5, 9]: unknown identifier `valeu`, did you mean `value`?"},
+		"underscore":   {expr: "my_value", err: "expression in rage [1, 8]: unknown identifier `my_value`"},
+		"no_match":     {expr: "foo", err: "expression in rage [1, 3]: unknown identifier `foo`"},
+		"short":        {expr: "y", err: "expression at [1]: unknown identifier `y`"},
+		"no_arguments": {expr: "sin", err: "expression in rage [1, 3]: unknown identifier `sin`"},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			_, err := e.Execute(tc.expr, 16)
+			assert.EqualError(t, err, tc.err)
+		})
+	}
+
+	result, err := e.Execute("sinh(0) + value2", 16)
+	assert.EqualError(t, err, "expression in rage [11, 16]: unknown identifier `value2`, did you mean `value`?")
+	assert.Equal(t, "", result)
+}
+
+func TestUnicode(t *testing.T) {
+	e := executor.NewExecutor(nil, nil)
+
+	testcases := map[string]struct {
+		expr   string
+		result string
+		err    string
+	}{
+		"pi":             {expr: "π", result: "3.1415926535897932"},
+		"multiplication": {expr: "2×π", result: "6.2831853071795865"},
+		"division":       {expr: "6÷3", result: "2"},
+		"minus":          {expr: "5−2", result: "3"},
+		"negation":       {expr: "−3²", result: "-9"},
+		"square":         {expr: "3²", result: "9"},
+		"cube":           {expr: "2³", result: "8"},
+		"square_root":    {expr: "√(9+16)", result: "5"},
+		"implicit_root":  {expr: "2√4", result: "4"},
+		"list_square":    {expr: "[1, 2]²", result: "[1, 4]"},
+		"list_root":      {expr: "√[4, 9]", result: "[2, 3]"},
+		"units":          {expr: "2 m² to cm²", result: "20000 cm^2"},
+		"degrees":        {expr: "sin(30°)", result: "0.5"},
+		"no_operand":     {expr: "√", err: "expression at [1]: expected value after `√`"},
+		"invalid":        {expr: "π + é", err: "expression at [5]: invalid symbol"},
+		"wide":           {expr: "1 ＋ 2", err: "expression in rage [3, 4]: invalid symbol"},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			result, err := e.Execute(tc.expr, 16)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.result, result)
+		})
+	}
+
+	_, err := e.Execute("π × é", 16)
+	var exprErr *executor.ExprError
+	require.ErrorAs(t, err, &exprErr)
+	assert.Equal(t, executor.Location{Start: 6, End: 8, StartColumn: 4, EndColumn: 5}, exprErr.Loc)
+}
+
+func TestScripts(t *testing.T) {
+	testcases := map[string]struct {
+		script  string
+		results []string
+		err     string
+	}{
+		"empty":       {script: "", results: nil},
+		"comments":    {script: "# comment\n\n  # another one\n", results: nil},
+		"single":      {script: "1 + 2", results: []string{"3"}},
+		"lines":       {script: "1 + 2\n3 * 4\n", results: []string{"3", "12"}},
+		"semicolons":  {script: "1 + 2; 3 * 4;", results: []string{"3", "12"}},
+		"crlf":        {script: "1 + 2\r\n3 * 4\r\n", results: []string{"3", "12"}},
+		"assignments": {script: "a = 2; b = a * 3\na + b", results: []string{"8"}},
+		"functions":   {script: "f(x) = x^2 # square\nf(3)", results: []string{"9"}},
+		"trailing":    {script: "1 + 2 # 3 * 4\n5", results: []string{"3", "5"}},
+		"multiline":   {script: "[1,\n 2,\n 3]\nsum(\n  4,\n  5\n)", results: []string{"[1, 2, 3]", "9"}},
+		"error":       {script: "1\n2 +\n3", results: []string{"1"}, err: "expression at [3]: expected value after `+`"},
+		"unknown":     {script: "a = 1\nb + a", results: nil, err: "expression at [1]: unknown identifier `b`"},
+		"invalid":     {script: "1\n2 ? 3", results: nil, err: "expression at [3]: invalid symbol"},
+		"unclosed":    {script: "(1 +\n2\n3 * 4", results: nil, err: "expression at [1]: expected operator, but got `3`"},
+		"later_define": {
+			script: "1; f(2); f(x) = x", results: []string{"1"}, err: "expression at [4]: unknown function `f/1`",
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			e := executor.NewExecutor(nil, nil)
+			results, err := e.ExecuteScript(tc.script, 16)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.results, results)
+		})
+	}
+
+	e := executor.NewExecutor(nil, nil)
+	_, err := e.ExecuteScript("a = 1\n\nb = [\n  a,\n  π + c\n]", 16)
+	var exprErr *executor.ExprError
+	require.ErrorAs(t, err, &exprErr)
+	assert.Equal(t, executor.Location{Start: 25, End: 26, Line: 4, StartColumn: 6, EndColumn: 7}, exprErr.Loc)
+
+	result, err := e.Execute("1 + 2\n", 16)
+	assert.NoError(t, err)
+	assert.Equal(t, "3", result)
+
+	result, err = e.Execute("1 + 2; 3", 16)
+	assert.EqualError(t, err, "expression at [8]: expected single statement")
+	assert.Equal(t, "", result)
+}
+
+func TestScriptStatements(t *testing.T) {
+	e := executor.NewExecutor(nil, nil)
+	statements := e.ExecuteStatements("a = 2 # two\nf(x) = x * a; f(3)\n  b + 1\n4", 16)
+	require.Len(t, statements, 4)
+
+	expected := []executor.StatementResult{
+		{Text: "a = 2", Loc: executor.Location{Start: 0, End: 5, Line: 0, StartColumn: 0, EndColumn: 5}},
+		{Text: "f(x) = x * a", Loc: executor.Location{Start: 12, End: 24, Line: 1, StartColumn: 0, EndColumn: 12}},
+		{
+			Text: "f(3)", Result: "6",
+			Loc: executor.Location{Start: 26, End: 30, Line: 1, StartColumn: 14, EndColumn: 18},
+		},
+		{Text: "b + 1", Loc: executor.Location{Start: 33, End: 38, Line: 2, StartColumn: 2, EndColumn: 7}},
+	}
+	for i, statement := range statements[:3] {
+		assert.NoError(t, statement.Err)
+		assert.Equal(t, expected[i], statement)
+	}
+
+	var exprErr *executor.ExprError
+	require.ErrorAs(t, statements[3].Err, &exprErr)
+	assert.Equal(t, executor.Location{Start: 33, End: 34, Line: 2, StartColumn: 2, EndColumn: 3}, exprErr.Loc)
+	statements[3].Err = nil
+	assert.Equal(t, expected[3], statements[3])
+
+	statements = e.ExecuteStatements("1\n2 ? 3", 16)
+	require.Len(t, statements, 1)
+	assert.Equal(t, "1\n2 ? 3", statements[0].Text)
+	assert.EqualError(t, statements[0].Err, "expression at [3]: invalid symbol")
+}
+
+func TestColumns(t *testing.T) {
+	env := executor.NewEnv()
+	env.SetColumns([]string{"3", " 4.5 ", "abc", "-2"})
+	e := executor.NewExecutor(nil, env)
+
+	testcases := map[string]struct {
+		expr   string
+		result string
+		err    string
+	}{
+		"single":     {expr: "$1", result: "3"},
+		"spaces":     {expr: "$2 * 2", result: "9"},
+		"expression": {expr: "$1 * $4 + $2", result: "-1.5"},
+		"implicit":   {expr: "2$1", result: "6"},
+		"function":   {expr: "max($1, $2, $4)", result: "4.5"},
+		"not_number": {expr: "$3", err: "expression in rage [1, 2]: apply variable `$3`: column 3 is not a number: `abc`"},
+		"missing":    {expr: "1 + $10", err: "expression in rage [5, 7]: apply variable `$10`: no column 10"},
+		"zero":       {expr: "$0", err: "expression in rage [1, 2]: invalid column `$0`, columns are numbered from 1"},
+		"no_number":  {expr: "$a", err: "expression at [1]: invalid symbol"},
+		"assignment": {expr: "$1 = 2", err: "expression in rage [1, 2]: invalid name `$1`"},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			result, err := e.Execute(tc.expr, 16)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.result, result)
+		})
+	}
+
+	program, err := e.Compile("$1 + $2")
+	require.NoError(t, err)
+
+	env.SetColumns([]string{"1", "2"})
+	value, err := program.Run(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "3", value.String())
+
+	env.SetColumns(nil)
+	_, err = program.Run(nil)
+	assert.EqualError(t, err, "expression in rage [1, 2]: apply variable `$1`: no column 1")
+}
+
+func TestDebugJSON(t *testing.T) {
+	debug := &debugger.Debugger{}
+	e := executor.NewExecutor(debug, nil)
+
+	_, err := e.Execute("2 × (1+π)", 16)
+	require.NoError(t, err)
+
+	data, err := json.Marshal(debug.Entries())
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"label": "Tokens", "value": [
+			{"text": "2", "kind": "number", "start": 0, "end": 1},
+			{"text": "*", "kind": "operator", "start": 2, "end": 4},
+			{"text": "(", "kind": "operator", "start": 5, "end": 6},
+			{"text": "1", "kind": "number", "start": 6, "end": 7},
+			{"text": "+", "kind": "operator", "start": 7, "end": 8},
+			{"text": "Pi", "kind": "identifier", "start": 8, "end": 10},
+			{"text": ")", "kind": "operator", "start": 10, "end": 11}
+		]},
+		{"label": "AST", "value": "(2 * (1 + Pi))"}
+	]`, string(data))
+
+	debug.Clean()
+	assert.Empty(t, debug.Entries())
+}
+
+func TestFormat(t *testing.T) {
+	type format = executor.Format
+	sci := executor.NotationScientific
+	eng := executor.NotationEngineering
+
+	testcases := map[string]struct {
+		format format
+		expr   string
+		result string
+	}{
+		"default":          {format: format{}, expr: "1234567.891", result: "1234567.891"},
+		"significant":      {format: format{SignificantDigits: 3}, expr: "1/7", result: "0.143"},
+		"significant_int":  {format: format{SignificantDigits: 3}, expr: "1234567", result: "1230000"},
+		"significant_tiny": {format: format{SignificantDigits: 2}, expr: "0.000123456", result: "0.00012"},
+		"carry":            {format: format{SignificantDigits: 3, TrailingZeros: true}, expr: "9.996", result: "10.0"},
+		"zeros":            {format: format{TrailingZeros: true}, expr: "1.5", result: "1.5000000000000000"},
+		"zeros_integer":    {format: format{SignificantDigits: 3, TrailingZeros: true}, expr: "2", result: "2.00"},
+		"scientific":       {format: format{Notation: sci}, expr: "12345.678", result: "1.2345678e4"},
+		"scientific_small": {format: format{Notation: sci, SignificantDigits: 3}, expr: "-0.00123456", result: "-1.23e-3"},
+		"scientific_carry": {format: format{Notation: sci, SignificantDigits: 2}, expr: "9.96", result: "1e1"},
+		"scientific_zero":  {format: format{Notation: sci}, expr: "0", result: "0e0"},
+		"engineering":      {format: format{Notation: eng}, expr: "12345.678", result: "12.345678e3"},
+		"engineering_neg":  {format: format{Notation: eng, SignificantDigits: 4}, expr: "0.00123456", result: "1.235e-3"},
+		"engineering_carry": {
+			format: format{Notation: eng, SignificantDigits: 3}, expr: "999.96", result: "1e3",
+		},
+		"group":         {format: format{GroupSeparator: ","}, expr: "-1234567.891", result: "-1,234,567.891"},
+		"group_short":   {format: format{GroupSeparator: ","}, expr: "123.4567", result: "123.4567"},
+		"decimal_comma": {format: format{GroupSeparator: ".", DecimalSeparator: ","}, expr: "1234.5", result: "1.234,5"},
+		"list":          {format: format{SignificantDigits: 2}, expr: "[1/3, 2/3]", result: "[0.33, 0.67]"},
+		"list_decimal":  {format: format{DecimalSeparator: ","}, expr: "[1.5, 2.5]", result: "[1,5; 2,5]"},
+		"list_group":    {format: format{GroupSeparator: ","}, expr: "[[1234, 5]]", result: "[[1,234; 5]]"},
+		"list_space":    {format: format{GroupSeparator: " "}, expr: "[1234, 5]", result: "[1 234, 5]"},
+		"quantity":      {format: format{Notation: sci}, expr: "1500 m", result: "1.5e3 m"},
+		"complex":       {format: format{SignificantDigits: 2}, expr: "1/3 + 2i/3", result: "0.33+0.67i"},
+		"tiny": {
+			format: format{SignificantDigits: 5}, expr: "1/(3*10^30)",
+			result: "0.00000000000000000000000000000033333",
+		},
+		"tiny_scientific": {format: format{Notation: sci}, expr: "1/(3*10^40)", result: "3.3333333333333333e-41"},
+		"tiny_list": {
+			format: format{Notation: sci, SignificantDigits: 2}, expr: "[1, 1/10^50]", result: "[1e0, 1e-50]",
+		},
+		"many_digits": {
+			format: format{SignificantDigits: 50}, expr: "Pi",
+			result: "3.1415926535897932384626433832795028841971693993751",
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			e := executor.NewExecutor(nil, nil)
+			require.NoError(t, e.SetFormat(tc.format))
+
+			result, err := e.Execute(tc.expr, 16)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.result, result)
+		})
+	}
+
+	e := executor.NewExecutor(nil, nil)
+	require.NoError(t, e.SetExactMode(executor.ExactFraction))
+	require.NoError(t, e.SetFormat(format{Notation: sci, SignificantDigits: 2, GroupSeparator: " "}))
+
+	result, err := e.Execute("12345/7", 16)
+	assert.NoError(t, err)
+	assert.Equal(t, "12 345/7", result)
+
+	value, err := e.Evaluate("12345/7", 16)
+	require.NoError(t, err)
+	assert.Equal(t, "12 345/7", e.FormatValue(value, 16, e.Format()))
+	assert.Equal(t, "12345/7", e.FormatValue(value, 16, format{}))
+
+	value, err = e.Evaluate("f(x) = x", 16)
+	assert.NoError(t, err)
+	assert.Nil(t, value)
+
+	assert.EqualError(t, e.SetFormat(format{SignificantDigits: -1}), "significant digits must be between 0 and 1000")
+	assert.EqualError(t, e.SetFormat(format{GroupSeparator: "."}), "group and decimal separators must be different")
+	assert.EqualError(t, e.SetFormat(format{DecimalSeparator: "5"}),
+		"invalid separator `5`, expected single character that is not a digit, letter or sign")
+	assert.EqualError(t, e.SetFormat(format{Notation: 5}), "unknown notation 5")
+	assert.Equal(t, format{Notation: sci, SignificantDigits: 2, GroupSeparator: " "}, e.Format())
+
+	separator, err := executor.ParseSeparator("apostrophe")
+	assert.NoError(t, err)
+	assert.Equal(t, "'", separator)
+
+	separator, err = executor.ParseSeparator("off")
+	assert.NoError(t, err)
+	assert.Equal(t, "", separator)
+
+	_, err = executor.ParseSeparator("--")
+	assert.Error(t, err)
+
+	notation, err := executor.ParseNotation("eng")
+	assert.NoError(t, err)
+	assert.Equal(t, eng, notation)
+}
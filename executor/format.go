@@ -0,0 +1,241 @@
+package executor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/mymmrac/mm/utils"
+)
+
+// Notation is a way decimal numbers are written in results
+type Notation int
+
+const (
+	NotationFixed       Notation = iota // Plain number, for example: `12345.6`
+	NotationScientific                  // One digit before the point, for example: `1.23456e4`
+	NotationEngineering                 // Exponent is a multiple of three, for example: `12.3456e3`
+)
+
+var notationNames = map[Notation]string{
+	NotationFixed:       "fixed",
+	NotationScientific:  "sci",
+	NotationEngineering: "eng",
+}
+
+// ParseNotation parses notation, one of `fixed`, `sci` or `eng`, empty string is fixed notation
+func ParseNotation(text string) (Notation, error) {
+	if text == "" {
+		return NotationFixed, nil
+	}
+
+	for notation, name := range notationNames {
+		if name == text {
+			return notation, nil
+		}
+	}
+	return NotationFixed, fmt.Errorf("invalid notation `%s`, expected `fixed`, `sci` or `eng`", text)
+}
+
+func (n Notation) String() string {
+	return notationNames[n]
+}
+
+func (n Notation) validate() error {
+	if _, ok := notationNames[n]; !ok {
+		return fmt.Errorf("unknown notation %d", n)
+	}
+	return nil
+}
+
+// separatorNames are names of separators that are hard to type or pass as arguments
+var separatorNames = map[string]string{
+	"comma":      ",",
+	"dot":        ".",
+	"space":      " ",
+	"underscore": "_",
+	"apostrophe": "'",
+}
+
+// ParseSeparator parses separator of digits, it is a single character or its name: `comma`, `dot`, `space`,
+// `underscore` or `apostrophe`, `off` and empty string mean no separator
+func ParseSeparator(text string) (string, error) {
+	if text == "" || text == "off" {
+		return "", nil
+	}
+	if separator, ok := separatorNames[text]; ok {
+		return separator, nil
+	}
+	if err := validateSeparator(text); err != nil {
+		return "", err
+	}
+	return text, nil
+}
+
+// validateSeparator checks that separator is a single character that can't be confused with number
+func validateSeparator(separator string) error {
+	r, size := utf8.DecodeRuneInString(separator)
+	if size != len(separator) || r == utf8.RuneError || r < 128 && (utils.IsLetter(byte(r)) || utils.IsDigit(byte(r))) ||
+		strings.ContainsRune("+-", r) {
+		return fmt.Errorf("invalid separator `%s`, expected single character that is not a digit, letter or sign",
+			separator)
+	}
+	return nil
+}
+
+// Format is a formatting of decimal numbers in results, zero value is the default format, rationals in exact mode
+// are formatted only with digits grouping and numbers in other bases are not affected
+type Format struct {
+	Notation          Notation
+	SignificantDigits int32  // Numbers are rounded to significant digits instead of digits after the point if positive
+	TrailingZeros     bool   // Fraction is padded with zeros up to precision or significant digits
+	GroupSeparator    string // Separates groups of three digits in integer part, empty means no grouping
+	DecimalSeparator  string // Separates integer part and fraction, empty means `.`
+}
+
+func (f Format) validate() error {
+	if err := f.Notation.validate(); err != nil {
+		return err
+	}
+	if f.SignificantDigits < 0 || f.SignificantDigits > MaxPrecision {
+		return fmt.Errorf("significant digits must be between 0 and %d", MaxPrecision)
+	}
+	if f.GroupSeparator != "" {
+		if err := validateSeparator(f.GroupSeparator); err != nil {
+			return err
+		}
+	}
+	if f.DecimalSeparator != "" {
+		if err := validateSeparator(f.DecimalSeparator); err != nil {
+			return err
+		}
+	}
+	if f.GroupSeparator == f.decimalSeparator() {
+		return fmt.Errorf("group and decimal separators must be different")
+	}
+	return nil
+}
+
+func (f Format) decimalSeparator() string {
+	if f.DecimalSeparator == "" {
+		return "."
+	}
+	return f.DecimalSeparator
+}
+
+// elementSeparator returns separator of list elements, it is `; ` if digits are separated by comma
+func (f Format) elementSeparator() string {
+	if f.GroupSeparator == "," || f.decimalSeparator() == "," {
+		return "; "
+	}
+	return ", "
+}
+
+// formatDecimal formats number rounded to significant digits if they are set or to the precision digits after the
+// point of mantissa otherwise
+func (f Format) formatDecimal(value decimal.Decimal, precision int32) string {
+	mantissa, exponent, decimals := f.round(value, precision)
+	// Rounding can carry into a new leading digit, for example: `9.99` is `10.0` with three significant digits, so
+	// rounded number is rounded again with its own exponent
+	mantissa, exponent, decimals = f.round(mantissa.Shift(exponent), precision)
+
+	var text string
+	if f.TrailingZeros && decimals > 0 {
+		text = mantissa.StringFixed(decimals)
+	} else {
+		text = mantissa.String()
+	}
+
+	integer, fraction, hasFraction := strings.Cut(text, ".")
+	sign, integer := "", strings.TrimPrefix(integer, "-")
+	if strings.HasPrefix(text, "-") {
+		sign = "-"
+	}
+
+	text = sign + f.groupDigits(integer)
+	if hasFraction {
+		text += f.decimalSeparator() + fraction
+	}
+	if f.Notation != NotationFixed {
+		text += "e" + strconv.Itoa(int(exponent))
+	}
+	return text
+}
+
+// round returns mantissa of number rounded to the number of decimals and exponent of the notation
+func (f Format) round(value decimal.Decimal, precision int32) (mantissa decimal.Decimal, exponent, decimals int32) {
+	switch f.Notation {
+	case NotationScientific:
+		exponent = leadingExponent(value)
+	case NotationEngineering:
+		exponent = leadingExponent(value)
+		exponent -= (exponent%3 + 3) % 3
+	}
+
+	mantissa = value.Shift(-exponent)
+	decimals = precision
+	if f.SignificantDigits > 0 {
+		decimals = f.SignificantDigits - 1 - leadingExponent(mantissa)
+	}
+	return mantissa.Round(decimals), exponent, decimals
+}
+
+// requiredPrecision returns the number of digits after the point the value must be evaluated with to be formatted
+// with the precision, small numbers need more digits when they are rounded to significant digits or have an exponent
+func (f Format) requiredPrecision(value Value, precision int32) int32 {
+	switch value := value.(type) {
+	case Rational:
+		return precision
+	case Quantity:
+		return f.decimalPrecision(value.value, precision)
+	case Complex:
+		return max(f.decimalPrecision(value.re, precision), f.decimalPrecision(value.im, precision))
+	case List:
+		required := precision
+		for _, element := range value.elements {
+			required = max(required, f.requiredPrecision(element, precision))
+		}
+		return required
+	default:
+		number, _ := magnitude(value, workingPrecision(precision))
+		return f.decimalPrecision(number, precision)
+	}
+}
+
+// decimalPrecision returns the number of digits after the point of the number rounded to the format
+func (f Format) decimalPrecision(value decimal.Decimal, precision int32) int32 {
+	if value.IsZero() && (f.SignificantDigits > 0 || f.Notation != NotationFixed) {
+		// Zero may be a small number rounded to the working precision
+		return MaxPrecision
+	}
+
+	_, exponent, decimals := f.round(value, precision)
+	return max(precision, decimals-exponent)
+}
+
+// leadingExponent returns power of ten of the first significant digit, it is zero for zero
+func leadingExponent(value decimal.Decimal) int32 {
+	if value.IsZero() {
+		return 0
+	}
+	return int32(value.NumDigits()) - 1 + value.Exponent()
+}
+
+// groupDigits separates groups of three digits of integer part starting from the right
+func (f Format) groupDigits(digits string) string {
+	if f.GroupSeparator == "" || len(digits) <= 3 {
+		return digits
+	}
+
+	var s strings.Builder
+	for i, digit := range digits {
+		if i != 0 && (len(digits)-i)%3 == 0 {
+			s.WriteString(f.GroupSeparator)
+		}
+		s.WriteRune(digit)
+	}
+	return s.String()
+}
@@ -2,27 +2,88 @@ package executor
 
 import (
 	"fmt"
+	"math"
+	"math/big"
 	"math/rand/v2"
 	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/shopspring/decimal"
 
 	"github.com/mymmrac/mm/utils"
 )
 
-var (
-	// Pi = [math.Pi]
-	constPi, _ = decimal.NewFromString("3.14159265358979323846264338327950288419716939937510582097494459")
-	// E = [math.E]
-	constE, _ = decimal.NewFromString("2.71828182845904523536028747135266249775724709369995957496696763")
+// constPi is the number Pi with more digits than the maximal working precision
+var constPi = decimal.RequireFromString(
+	"3." +
+		"1415926535897932384626433832795028841971693993751058209749445923078164062862089986280348253421170679" +
+		"8214808651328230664709384460955058223172535940812848111745028410270193852110555964462294895493038196" +
+		"4428810975665933446128475648233786783165271201909145648566923460348610454326648213393607260249141273" +
+		"7245870066063155881748815209209628292540917153643678925903600113305305488204665213841469519415116094" +
+		"3305727036575959195309218611738193261179310511854807446237996274956735188575272489122793818301194912" +
+		"9833673362440656643086021394946395224737190702179860943702770539217176293176752384674818467669405132" +
+		"0005681271452635608277857713427577896091736371787214684409012249534301465495853710507922796892589235" +
+		"4201995611212902196086403441815981362977477130996051870721134999999837297804995105973173281609631859" +
+		"5024459455346908302642522308253344685035261931188171010003137838752886587533208381420617177669147303" +
+		"5982534904287554687311595628638823537875937519577818577805321712268066130019278766111959092164201989" +
+		"3809525720106548586327886593615338182796823030195203530185296899577362259941389124972177528347913151",
 )
 
+// pi returns the number Pi rounded to the precision
+func pi(precision int32) decimal.Decimal {
+	return constPi.Round(precision)
+}
+
 type Identifier struct {
 	text     string
 	name     string
 	variable bool
-	arity    uint
-	apply    func(stack *utils.Stack[decimal.Decimal]) error
+	arity    arity
+	params   []string // Names of parameters used in documentation, the last one is repeated by variadic functions
+	apply    func(s *scope, args []Value) (Value, error)
+
+	// lazy is used instead of apply by functions that evaluate their arguments on demand
+	lazy func(args []func() (Value, error)) (Value, error)
+}
+
+// unbounded is the maximal number of arguments of variadic functions
+const unbounded = math.MaxUint
+
+// arity is the range of accepted number of arguments
+type arity struct {
+	min, max uint
+}
+
+func exactly(n uint) arity {
+	return arity{min: n, max: n}
+}
+
+func between(from, to uint) arity {
+	return arity{min: from, max: to}
+}
+
+func atLeast(n uint) arity {
+	return arity{min: n, max: unbounded}
+}
+
+func (a arity) accepts(args uint) bool {
+	return a.min <= args && args <= a.max
+}
+
+func (a arity) overlaps(other arity) bool {
+	return a.min <= other.max && other.min <= a.max
+}
+
+func (a arity) String() string {
+	switch {
+	case a.max == unbounded:
+		return "at least " + strconv.FormatUint(uint64(a.min), 10)
+	case a.min == a.max:
+		return strconv.FormatUint(uint64(a.min), 10)
+	default:
+		return strconv.FormatUint(uint64(a.min), 10) + " to " + strconv.FormatUint(uint64(a.max), 10)
+	}
 }
 
 var knownIdentifiers = []Identifier{
@@ -30,200 +91,677 @@ var knownIdentifiers = []Identifier{
 		text:     "Pi",
 		name:     "number Pi",
 		variable: true,
-		apply:    applyConstantIdent(constPi),
+		apply: func(s *scope, _ []Value) (Value, error) {
+			return NewNumber(pi(s.precision)), nil
+		},
 	},
 	{
 		text:     "e",
 		name:     "number e",
 		variable: true,
-		apply:    applyConstantIdent(constE),
+		apply: func(s *scope, _ []Value) (Value, error) {
+			value, err := exponential(decimal.NewFromInt(1), s.precision)
+			if err != nil {
+				return nil, err
+			}
+			return NewNumber(value), nil
+		},
 	},
 	{
-		text:  "sqrt",
-		name:  "square root",
-		arity: 1,
-		apply: applyUnaryOp(func(v1 decimal.Decimal) (decimal.Decimal, error) {
-			if v1.IsNegative() {
-				return decimal.Zero, fmt.Errorf("square root of negative number")
+		text:   "sqrt",
+		name:   "square root",
+		arity:  exactly(1),
+		params: []string{"x"},
+		apply:  sqrt,
+	},
+	{
+		text:   "abs",
+		name:   "absolute value, modulus of complex number",
+		arity:  exactly(1),
+		params: []string{"x"},
+		apply:  modulus,
+	},
+	{
+		text:   "re",
+		name:   "real part",
+		arity:  exactly(1),
+		params: []string{"z"},
+		apply: applyComplexFunc(func(c Complex, _ int32) (Value, error) {
+			return NewNumber(c.re), nil
+		}, applyExactOp(copyRational, decimal.Decimal.Copy)),
+	},
+	{
+		text:   "im",
+		name:   "imaginary part",
+		arity:  exactly(1),
+		params: []string{"z"},
+		apply: applyComplexFunc(func(c Complex, _ int32) (Value, error) {
+			return NewNumber(c.im), nil
+		}, applyExactOp(func(_ *big.Rat) *big.Rat {
+			return new(big.Rat)
+		}, func(_ decimal.Decimal) decimal.Decimal {
+			return decimal.Zero
+		})),
+	},
+	{
+		text:   "conj",
+		name:   "complex conjugate",
+		arity:  exactly(1),
+		params: []string{"z"},
+		apply: applyComplexFunc(func(c Complex, _ int32) (Value, error) {
+			return c.conj(), nil
+		}, applyExactOp(copyRational, decimal.Decimal.Copy)),
+	},
+	{
+		text:   "arg",
+		name:   "argument of complex number (-180 deg, 180 deg]",
+		arity:  exactly(1),
+		params: []string{"z"},
+		apply:  argument,
+	},
+	{
+		text:   "rect",
+		name:   "complex number from polar coordinates",
+		arity:  exactly(2),
+		params: []string{"r", "phi"},
+		apply: func(s *scope, args []Value) (Value, error) {
+			r, err := toDecimal(args[0], s.precision)
+			if err != nil {
+				return nil, err
+			}
+			phi, turn, err := angle(s, args[1])
+			if err != nil {
+				return nil, err
 			}
-			return v1.PowWithPrecision(decimal.NewFromFloat(0.5), 32)
+			sin, cos := sinCosAngle(phi, turn, seriesPrecision(s.precision)+integerDigits(r))
+			return NewComplex(r.Mul(cos).Round(s.precision), r.Mul(sin).Round(s.precision)), nil
+		},
+	},
+	{
+		text:   "polar",
+		name:   "polar coordinates [r, phi] of complex number",
+		arity:  exactly(1),
+		params: []string{"z"},
+		apply: func(s *scope, args []Value) (Value, error) {
+			r, err := modulus(s, args)
+			if err != nil {
+				return nil, err
+			}
+			phi, err := argument(s, args)
+			if err != nil {
+				return nil, err
+			}
+			return List{elements: []Value{r, phi}}, nil
+		},
+	},
+	{
+		text:   "round",
+		name:   "round to integer or to digits after the point",
+		arity:  between(1, 2),
+		params: []string{"x", "digits"},
+		apply: applyOptionalArg(
+			applyExactOp(roundRational, roundDecimal),
+			applyKeepUnit(applyBinaryOp(func(v1, v2 decimal.Decimal) (decimal.Decimal, error) {
+				if !v2.IsInteger() {
+					return decimal.Zero, fmt.Errorf("the second argument must be an integer")
+				}
+				return v1.Round(int32(v2.IntPart())), nil
+			})),
+		),
+	},
+	{
+		text:   "roundUp",
+		name:   "round up (away from zero) to integer or to digits after the point",
+		arity:  between(1, 2),
+		params: []string{"x", "digits"},
+		apply: applyOptionalArg(
+			applyKeepUnit(applyUnaryOp(func(v1 decimal.Decimal) (decimal.Decimal, error) {
+				return v1.RoundUp(0), nil
+			})),
+			applyKeepUnit(applyBinaryOp(func(v1, v2 decimal.Decimal) (decimal.Decimal, error) {
+				if !v2.IsInteger() {
+					return decimal.Zero, fmt.Errorf("the second argument must be an integer")
+				}
+				return v1.RoundUp(int32(v2.IntPart())), nil
+			})),
+		),
+	},
+	{
+		text:   "floor",
+		name:   "floor",
+		arity:  exactly(1),
+		params: []string{"x"},
+		apply:  applyExactOp(floorRational, decimal.Decimal.Floor),
+	},
+	{
+		text:   "ceil",
+		name:   "ceil",
+		arity:  exactly(1),
+		params: []string{"x"},
+		apply:  applyExactOp(ceilRational, decimal.Decimal.Ceil),
+	},
+	{
+		text:   "trunc",
+		name:   "integer part (round toward zero)",
+		arity:  exactly(1),
+		params: []string{"x"},
+		apply:  applyExactOp(truncRational, truncDecimal),
+	},
+	{
+		text:   "frac",
+		name:   "fractional part, `x - trunc(x)`",
+		arity:  exactly(1),
+		params: []string{"x"},
+		apply:  applyExactOp(fracRational, fracDecimal),
+	},
+	{
+		text:   "sign",
+		name:   "sign, -1, 0 or 1",
+		arity:  exactly(1),
+		params: []string{"x"},
+		apply: func(_ *scope, args []Value) (Value, error) {
+			return sign(args[0])
+		},
+	},
+	{
+		text:   "sin",
+		name:   "sine",
+		arity:  exactly(1),
+		params: []string{"angle"},
+		apply:  sine,
+	},
+	{
+		text:   "cos",
+		name:   "cosine",
+		arity:  exactly(1),
+		params: []string{"angle"},
+		apply:  cosine,
+	},
+	{
+		text:   "tan",
+		name:   "tangent",
+		arity:  exactly(1),
+		params: []string{"angle"},
+		apply:  tangent,
+	},
+	{
+		text:   "asin",
+		name:   "arcsine [-90 deg, 90 deg]",
+		arity:  exactly(1),
+		params: []string{"x"},
+		apply: applyInverseTrig(func(args []decimal.Decimal, precision int32) (decimal.Decimal, error) {
+			return arcSine(args[0], precision)
 		}),
 	},
 	{
-		text:  "abs",
-		name:  "absolute value",
-		arity: 1,
-		apply: applyUnaryOp(func(v1 decimal.Decimal) (decimal.Decimal, error) {
-			return v1.Abs(), nil
+		text:   "acos",
+		name:   "arccosine [0 deg, 180 deg]",
+		arity:  exactly(1),
+		params: []string{"x"},
+		apply: applyInverseTrig(func(args []decimal.Decimal, precision int32) (decimal.Decimal, error) {
+			return arcCosine(args[0], precision)
 		}),
 	},
 	{
-		text:  "round",
-		name:  "round",
-		arity: 1,
-		apply: applyUnaryOp(func(v1 decimal.Decimal) (decimal.Decimal, error) {
-			return v1.Round(0), nil
+		text:   "atan",
+		name:   "arctangent (-90 deg, 90 deg)",
+		arity:  exactly(1),
+		params: []string{"x"},
+		apply: applyInverseTrig(func(args []decimal.Decimal, precision int32) (decimal.Decimal, error) {
+			return atan(args[0], precision), nil
 		}),
 	},
 	{
-		text:  "round",
-		name:  "round",
-		arity: 2,
-		apply: applyBinaryOp(func(v1, v2 decimal.Decimal) (decimal.Decimal, error) {
-			if !v2.IsInteger() {
-				return decimal.Zero, fmt.Errorf("the second argument must be an integer")
-			}
-			return v1.Round(int32(v2.IntPart())), nil
+		text:   "atan2",
+		name:   "angle of point (x, y) (-180 deg, 180 deg]",
+		arity:  exactly(2),
+		params: []string{"y", "x"},
+		apply: applyInverseTrig(func(args []decimal.Decimal, precision int32) (decimal.Decimal, error) {
+			return atan2(args[0], args[1], precision), nil
 		}),
 	},
 	{
-		text:  "roundUp",
-		name:  "round up",
-		arity: 1,
-		apply: applyUnaryOp(func(v1 decimal.Decimal) (decimal.Decimal, error) {
-			return v1.RoundUp(0), nil
+		text:   "sinh",
+		name:   "hyperbolic sine",
+		arity:  exactly(1),
+		params: []string{"x"},
+		apply: applyUnaryFunc(func(v1 decimal.Decimal, precision int32) (decimal.Decimal, error) {
+			sinh, _, err := sinhCosh(v1, precision)
+			return sinh, err
 		}),
 	},
 	{
-		text:  "roundUp",
-		name:  "round up",
-		arity: 2,
-		apply: applyBinaryOp(func(v1, v2 decimal.Decimal) (decimal.Decimal, error) {
-			if !v2.IsInteger() {
-				return decimal.Zero, fmt.Errorf("the second argument must be an integer")
-			}
-			return v1.RoundUp(int32(v2.IntPart())), nil
+		text:   "cosh",
+		name:   "hyperbolic cosine",
+		arity:  exactly(1),
+		params: []string{"x"},
+		apply: applyUnaryFunc(func(v1 decimal.Decimal, precision int32) (decimal.Decimal, error) {
+			_, cosh, err := sinhCosh(v1, precision)
+			return cosh, err
 		}),
 	},
 	{
-		text:  "floor",
-		name:  "floor",
-		arity: 1,
-		apply: applyUnaryOp(func(v1 decimal.Decimal) (decimal.Decimal, error) {
-			return v1.Floor(), nil
+		text:   "tanh",
+		name:   "hyperbolic tangent",
+		arity:  exactly(1),
+		params: []string{"x"},
+		apply:  applyUnaryFunc(hyperbolicTangent),
+	},
+	{
+		text:   "asinh",
+		name:   "inverse hyperbolic sine",
+		arity:  exactly(1),
+		params: []string{"x"},
+		apply:  applyUnaryFunc(inverseSinh),
+	},
+	{
+		text:   "acosh",
+		name:   "inverse hyperbolic cosine",
+		arity:  exactly(1),
+		params: []string{"x"},
+		apply:  applyUnaryFunc(inverseCosh),
+	},
+	{
+		text:   "atanh",
+		name:   "inverse hyperbolic tangent",
+		arity:  exactly(1),
+		params: []string{"x"},
+		apply:  applyUnaryFunc(inverseTanh),
+	},
+	{
+		text:   "ln",
+		name:   "natural logarithm",
+		arity:  exactly(1),
+		params: []string{"x"},
+		apply: applyComplexFunc(func(c Complex, precision int32) (Value, error) {
+			result, err := c.ln(precision)
+			if err != nil {
+				return nil, err
+			}
+			return NewComplex(result.re, result.im), nil
+		}, applyUnaryFunc(naturalLog)),
+	},
+	{
+		text:   "log10",
+		name:   "decimal logarithm",
+		arity:  exactly(1),
+		params: []string{"x"},
+		apply: applyUnaryFunc(func(v1 decimal.Decimal, precision int32) (decimal.Decimal, error) {
+			return logarithm(v1, decimal.NewFromInt(10), precision)
 		}),
 	},
 	{
-		text:  "ceil",
-		name:  "ceil",
-		arity: 1,
-		apply: applyUnaryOp(func(v1 decimal.Decimal) (decimal.Decimal, error) {
-			return v1.Ceil(), nil
+		text:   "log",
+		name:   "logarithm to the base",
+		arity:  exactly(2),
+		params: []string{"x", "base"},
+		apply:  applyBinaryFunc(logarithm),
+	},
+	{
+		text:   "exp",
+		name:   "exponent, `e ^ x`",
+		arity:  exactly(1),
+		params: []string{"x"},
+		apply:  applyComplexFunc(Complex.exp, applyUnaryFunc(exponential)),
+	},
+	{
+		text:   "rad",
+		name:   "degrees to radians",
+		arity:  exactly(1),
+		params: []string{"degrees"},
+		apply: applyUnaryFunc(func(v1 decimal.Decimal, precision int32) (decimal.Decimal, error) {
+			if v1.IsZero() {
+				return decimal.Zero, nil
+			}
+			series := seriesPrecision(precision) + integerDigits(v1)
+			return pi(series).Mul(v1).DivRound(decimal.NewFromInt(180), precision), nil
 		}),
 	},
 	{
-		text:  "sin",
-		name:  "sine",
-		arity: 1,
-		apply: applyUnaryOp(func(v1 decimal.Decimal) (decimal.Decimal, error) {
-			return v1.Sin(), nil
+		text:   "deg",
+		name:   "radians to degrees",
+		arity:  exactly(1),
+		params: []string{"radians"},
+		apply: applyUnaryFunc(func(v1 decimal.Decimal, precision int32) (decimal.Decimal, error) {
+			series := seriesPrecision(precision) + integerDigits(v1)
+			return v1.Mul(decimal.NewFromInt(180)).DivRound(pi(series), precision), nil
 		}),
 	},
 	{
-		text:  "cos",
-		name:  "cosine",
-		arity: 1,
-		apply: applyUnaryOp(func(v1 decimal.Decimal) (decimal.Decimal, error) {
-			return v1.Cos(), nil
+		text:   "cbrt",
+		name:   "cube root",
+		arity:  exactly(1),
+		params: []string{"x"},
+		apply: applyUnaryFunc(func(v1 decimal.Decimal, precision int32) (decimal.Decimal, error) {
+			return nthRoot(v1, decimal.NewFromInt(3), precision)
 		}),
 	},
 	{
-		text:  "tan",
-		name:  "tangent",
-		arity: 1,
-		apply: applyUnaryOp(func(v1 decimal.Decimal) (decimal.Decimal, error) {
-			return v1.Tan(), nil
+		text:   "nthroot",
+		name:   "root of the degree, odd roots of negative numbers are negative",
+		arity:  exactly(2),
+		params: []string{"x", "n"},
+		apply:  applyBinaryFunc(nthRoot),
+	},
+	{
+		text:   "hypot",
+		name:   "hypotenuse, `sqrt(x^2 + y^2)`",
+		arity:  exactly(2),
+		params: []string{"x", "y"},
+		apply:  applyValueOp(hypot),
+	},
+	{
+		text:   "min",
+		name:   "minimum",
+		arity:  atLeast(1),
+		params: []string{"values"},
+		apply: applyValuesOrList(func(s *scope, elements []Value) (Value, error) {
+			return extremum(elements, -1, s.precision)
 		}),
 	},
 	{
-		text:  "atan",
-		name:  "arc tangent",
-		arity: 1,
-		apply: applyUnaryOp(func(v1 decimal.Decimal) (decimal.Decimal, error) {
-			return v1.Atan(), nil
+		text:   "max",
+		name:   "maximum",
+		arity:  atLeast(1),
+		params: []string{"values"},
+		apply: applyValuesOrList(func(s *scope, elements []Value) (Value, error) {
+			return extremum(elements, 1, s.precision)
 		}),
 	},
 	{
-		text:  "rad",
-		name:  "radian",
-		arity: 1,
-		apply: applyUnaryOp(func(v1 decimal.Decimal) (decimal.Decimal, error) {
-			if v1.IsZero() {
-				return decimal.Zero, nil
-			}
-			return constPi.Mul(v1).DivRound(decimal.NewFromInt(180), defaultPrecision), nil
+		text:   "len",
+		name:   "length of list",
+		arity:  exactly(1),
+		params: []string{"list"},
+		apply: applyListArg(func(elements []Value) (Value, error) {
+			return NewNumber(decimal.NewFromInt(int64(len(elements)))), nil
 		}),
 	},
 	{
-		text:  "min",
-		name:  "minimum",
-		arity: 2,
-		apply: applyBinaryOp(func(v1, v2 decimal.Decimal) (decimal.Decimal, error) {
-			if v1.Cmp(v2) < 0 {
-				return v1, nil
-			}
-			return v2, nil
+		text:   "sum",
+		name:   "sum",
+		arity:  atLeast(1),
+		params: []string{"values"},
+		apply:  applyValuesOrList(sum),
+	},
+	{
+		text:   "mean",
+		name:   "arithmetic mean",
+		arity:  atLeast(1),
+		params: []string{"values"},
+		apply:  applyValuesOrList(mean),
+	},
+	{
+		text:   "avg",
+		name:   "arithmetic mean, same as `mean`",
+		arity:  atLeast(1),
+		params: []string{"values"},
+		apply:  applyValuesOrList(mean),
+	},
+	{
+		text:   "median",
+		name:   "median",
+		arity:  atLeast(1),
+		params: []string{"values"},
+		apply:  applyValuesOrList(median),
+	},
+	{
+		text:   "stddev",
+		name:   "sample standard deviation",
+		arity:  atLeast(1),
+		params: []string{"values"},
+		apply:  applyValuesOrList(stddev),
+	},
+	{
+		text:   "pctchange",
+		name:   "percent change from `a` to `b`, `(b - a) / a * 100`",
+		arity:  exactly(2),
+		params: []string{"a", "b"},
+		apply:  percentChange,
+	},
+	{
+		text:   "gcd",
+		name:   "greatest common divisor",
+		arity:  atLeast(1),
+		params: []string{"values"},
+		apply:  applyValuesOrList(gcd),
+	},
+	{
+		text:   "lcm",
+		name:   "least common multiple",
+		arity:  atLeast(1),
+		params: []string{"values"},
+		apply:  applyValuesOrList(lcm),
+	},
+	{
+		text:   "factorial",
+		name:   "factorial, `n!`",
+		arity:  exactly(1),
+		params: []string{"n"},
+		apply:  applyIntegerArgs(factorial),
+	},
+	{
+		text:   "nCr",
+		name:   "number of combinations of `k` items out of `n`, binomial coefficient",
+		arity:  exactly(2),
+		params: []string{"n", "k"},
+		apply:  applyIntegerArgs(combinations),
+	},
+	{
+		text:   "nPr",
+		name:   "number of permutations of `k` items out of `n`",
+		arity:  exactly(2),
+		params: []string{"n", "k"},
+		apply:  applyIntegerArgs(permutations),
+	},
+	{
+		text:   "isPrime",
+		name:   "1 if integer is prime, 0 otherwise",
+		arity:  exactly(1),
+		params: []string{"n"},
+		apply:  isPrime,
+	},
+	{
+		text:   "nextPrime",
+		name:   "the smallest prime greater than `n`",
+		arity:  exactly(1),
+		params: []string{"n"},
+		apply:  applyIntegerArgs(nextPrime),
+	},
+	{
+		text:   "factor",
+		name:   "list of prime factors in ascending order, for example: `factor(12)` is `[2, 2, 3]`",
+		arity:  exactly(1),
+		params: []string{"n"},
+		apply:  factor,
+	},
+	{
+		text:   "modpow",
+		name:   "`base ^ exp` modulo `m`",
+		arity:  exactly(3),
+		params: []string{"base", "exp", "m"},
+		apply:  applyIntegerArgs(modularPower),
+	},
+	{
+		text:   "modinv",
+		name:   "modular inverse, `x` such that `a * x % m == 1`",
+		arity:  exactly(2),
+		params: []string{"a", "m"},
+		apply:  applyIntegerArgs(modularInverse),
+	},
+	{
+		text:   "fib",
+		name:   "Fibonacci number, `fib(0) = 0`, `fib(1) = 1`",
+		arity:  exactly(1),
+		params: []string{"n"},
+		apply:  applyIntegerArgs(fibonacci),
+	},
+	{
+		text:   "transpose",
+		name:   "transposed matrix",
+		arity:  exactly(1),
+		params: []string{"matrix"},
+		apply: applyMatrixArg(func(m matrix) (Value, error) {
+			return m.transpose().value(), nil
 		}),
 	},
 	{
-		text:  "max",
-		name:  "maximum",
-		arity: 2,
-		apply: applyBinaryOp(func(v1, v2 decimal.Decimal) (decimal.Decimal, error) {
-			if v2.Cmp(v1) < 0 {
-				return v1, nil
+		text:   "det",
+		name:   "determinant",
+		arity:  exactly(1),
+		params: []string{"matrix"},
+		apply: func(s *scope, args []Value) (Value, error) {
+			return determinant(args[0], s.precision)
+		},
+	},
+	{
+		text:   "inverse",
+		name:   "inverse matrix",
+		arity:  exactly(1),
+		params: []string{"matrix"},
+		apply: func(s *scope, args []Value) (Value, error) {
+			return inverse(args[0], s.precision)
+		},
+	},
+	{
+		text:   "identity",
+		name:   "identity matrix of the size",
+		arity:  exactly(1),
+		params: []string{"size"},
+		apply: func(s *scope, args []Value) (Value, error) {
+			return identity(args[0], s.precision)
+		},
+	},
+	{
+		text:   "solve",
+		name:   "solution `x` of linear system `A * x = b`, where `b` is a vector or a matrix",
+		arity:  exactly(2),
+		params: []string{"A", "b"},
+		apply:  applyValueOp(solve),
+	},
+	{
+		text:   "if",
+		name:   "value of `then` if condition is true, `else` otherwise (only one of them is evaluated)",
+		arity:  exactly(3),
+		params: []string{"condition", "then", "else"},
+		lazy: func(args []func() (Value, error)) (Value, error) {
+			condition, err := isTrue(args[0]())
+			if err != nil {
+				return nil, err
 			}
-			return v2, nil
-		}),
+
+			if condition {
+				return args[1]()
+			}
+			return args[2]()
+		},
 	},
 	{
 		text:  "rand",
-		name:  "random number",
-		arity: 0,
-		apply: applyNullaryIdent(func() (decimal.Decimal, error) {
-			return decimal.NewFromFloat(rand.Float64()), nil
+		name:  "random value [0, 1)",
+		arity: exactly(0),
+		apply: applyNullaryIdent(func() (Value, error) {
+			return NewNumber(decimal.NewFromFloat(rand.Float64())), nil
 		}),
 	},
 }
 
+// knownUniqueIdentifiers are sorted names of built-in identifiers
 var knownUniqueIdentifiers []string
 
 func init() {
-	for _, identifier := range knownIdentifiers {
-		utils.Assert(!utils.IsDigit(identifier.text[0]),
-			fmt.Sprintf("identifier `%s` must not start with a digit", identifier.text),
+	for i, identifier := range knownIdentifiers {
+		utils.Assert(!utils.IsDigit(identifier.text[0]) && utils.IsWord(identifier.text),
+			fmt.Sprintf("identifier `%s` must be a word that doesn't start with a digit", identifier.text),
 		)
-		utils.Assert(identifier.variable && identifier.arity == 0 || !identifier.variable,
+		utils.Assert(identifier.variable && identifier.arity == exactly(0) || !identifier.variable,
 			fmt.Sprintf("identifier `%s` must have arity 0 if it is variable", identifier.text),
 		)
+		utils.Assert(identifier.variable || identifier.arity.accepts(uint(len(identifier.params))),
+			fmt.Sprintf("identifier `%s` must have a name for each parameter", identifier.text),
+		)
 
-		key := fmt.Sprintf("%s/%d", identifier.text, identifier.arity)
-		utils.Assert(!uniqueness[key], fmt.Sprintf("identifier `%s` already exists", key))
-		uniqueness[key] = true
+		for _, other := range knownIdentifiers[:i] {
+			utils.Assert(other.text != identifier.text || other.variable != identifier.variable ||
+				!other.arity.overlaps(identifier.arity),
+				fmt.Sprintf("identifier `%s` already exists", identifier.text),
+			)
+		}
 
 		knownUniqueIdentifiers = append(knownUniqueIdentifiers, identifier.text)
 	}
 
-	slices.SortFunc(knownUniqueIdentifiers, func(a, b string) int {
-		return len(b) - len(a)
-	})
+	slices.Sort(knownUniqueIdentifiers)
 	knownUniqueIdentifiers = slices.Compact(knownUniqueIdentifiers)
 }
 
-func applyConstantIdent(constant decimal.Decimal) func(stack *utils.Stack[decimal.Decimal]) error {
-	return func(stack *utils.Stack[decimal.Decimal]) error {
-		stack.Push(constant)
-		return nil
+// FunctionDoc describes built-in function
+type FunctionDoc struct {
+	Signature   string // For example: `round(x, [digits])` or `min(values...)`
+	Description string
+}
+
+// Functions returns documentation of built-in functions in order of their definition
+func Functions() []FunctionDoc {
+	var docs []FunctionDoc
+	for _, identifier := range knownIdentifiers {
+		if identifier.variable {
+			continue
+		}
+
+		params := slices.Clone(identifier.params)
+		for i := range params {
+			switch {
+			case identifier.arity.max == unbounded && i == len(params)-1:
+				params[i] += "..."
+			case uint(i) >= identifier.arity.min:
+				params[i] = "[" + params[i] + "]"
+			}
+		}
+
+		docs = append(docs, FunctionDoc{
+			Signature:   identifier.text + "(" + strings.Join(params, ", ") + ")",
+			Description: strings.ToUpper(identifier.name[:1]) + identifier.name[1:],
+		})
 	}
+	return docs
 }
 
-func applyNullaryIdent(apply func() (decimal.Decimal, error)) func(stack *utils.Stack[decimal.Decimal]) error {
-	return func(stack *utils.Stack[decimal.Decimal]) error {
-		result, err := apply()
-		if err != nil {
-			return err
+func applyConstantIdent(constant Value) func(s *scope, args []Value) (Value, error) {
+	return func(_ *scope, _ []Value) (Value, error) {
+		return constant, nil
+	}
+}
+
+func applyNullaryIdent(apply func() (Value, error)) func(s *scope, args []Value) (Value, error) {
+	return func(_ *scope, _ []Value) (Value, error) {
+		return apply()
+	}
+}
+
+// applyOptionalArg applies the first function if the optional argument is omitted, otherwise the second one
+func applyOptionalArg(
+	apply, applyWithOptional func(s *scope, args []Value) (Value, error),
+) func(s *scope, args []Value) (Value, error) {
+	return func(s *scope, args []Value) (Value, error) {
+		if len(args) == 1 {
+			return apply(s, args)
+		}
+		return applyWithOptional(s, args)
+	}
+}
+
+// applyValuesOrList applies function to the arguments, or to elements of the list if it is the only argument, for
+// example: `sum(1, 2, 3)` or `sum([1, 2, 3])`
+func applyValuesOrList(
+	apply func(s *scope, elements []Value) (Value, error),
+) func(s *scope, args []Value) (Value, error) {
+	return func(s *scope, args []Value) (Value, error) {
+		if len(args) == 1 {
+			if list, ok := args[0].(List); ok {
+				return apply(s, list.elements)
+			}
 		}
-		stack.Push(result)
-		return nil
+		return apply(s, args)
 	}
 }
@@ -0,0 +1,356 @@
+package executor
+
+import (
+	"fmt"
+	"math/big"
+	"slices"
+
+	"github.com/shopspring/decimal"
+)
+
+// maxIntegerArgument is the maximal argument of factorial and Fibonacci numbers and the maximal number of factors in
+// combinations and permutations, larger results take too long to compute and print
+const maxIntegerArgument = 1 << 16
+
+// maxRhoIterations is the maximal number of iterations spent on finding one factor of a large number
+const maxRhoIterations = 1 << 18
+
+// trialDivisionLimit is the largest divisor factorization checks before switching to Pollard's rho algorithm
+const trialDivisionLimit = 1 << 12
+
+var argumentOrdinals = []string{"first", "second", "third"}
+
+// newInteger creates integer value, in exact mode it is a rational
+func newInteger(value *big.Int, exact bool) Value {
+	result := decimal.NewFromBigInt(value, 0)
+	if exact {
+		return newRational(result)
+	}
+	return NewNumber(result)
+}
+
+// integerArgs converts arguments into integers, error names the argument that is not an integer
+func integerArgs(args []Value, precision int32) ([]*big.Int, error) {
+	values := make([]*big.Int, len(args))
+	for i, arg := range args {
+		value, err := toDecimal(arg, precision)
+		if err != nil {
+			return nil, err
+		}
+		if !value.IsInteger() {
+			if len(args) == 1 {
+				return nil, fmt.Errorf("argument must be an integer")
+			}
+			return nil, fmt.Errorf("the %s argument must be an integer", argumentOrdinals[i])
+		}
+		values[i] = value.BigInt()
+	}
+	return values, nil
+}
+
+// applyIntegerArgs applies function to arguments that must be integers
+func applyIntegerArgs(
+	apply func(args []*big.Int) (*big.Int, error),
+) func(s *scope, args []Value) (Value, error) {
+	return func(s *scope, args []Value) (Value, error) {
+		values, err := integerArgs(args, s.precision)
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := apply(values)
+		if err != nil {
+			return nil, err
+		}
+		return newInteger(result, s.exact), nil
+	}
+}
+
+// smallInteger converts non-negative integer into int64, it must not be greater than maxIntegerArgument
+func smallInteger(v *big.Int, name string) (int64, error) {
+	if v.Sign() < 0 {
+		return 0, fmt.Errorf("%s must not be negative", name)
+	}
+	if v.Cmp(big.NewInt(maxIntegerArgument)) > 0 {
+		return 0, fmt.Errorf("%s must not be greater than %d", name, maxIntegerArgument)
+	}
+	return v.Int64(), nil
+}
+
+// gcd returns greatest common divisor of integers, it is always non-negative
+func gcd(s *scope, elements []Value) (Value, error) {
+	return foldIntegers(s, elements, big.NewInt(0), func(result, value *big.Int) {
+		result.GCD(nil, nil, result, value)
+	})
+}
+
+// lcm returns least common multiple of integers, it is always non-negative and zero if any of integers is zero
+func lcm(s *scope, elements []Value) (Value, error) {
+	return foldIntegers(s, elements, big.NewInt(1), func(result, value *big.Int) {
+		if result.Sign() == 0 || value.Sign() == 0 {
+			result.SetInt64(0)
+			return
+		}
+		divisor := new(big.Int).GCD(nil, nil, result, value)
+		result.Mul(result, value.Quo(value, divisor))
+	})
+}
+
+// foldIntegers combines absolute values of integer elements into the initial value
+func foldIntegers(s *scope, elements []Value, initial *big.Int, fold func(result, value *big.Int)) (Value, error) {
+	if len(elements) == 0 {
+		return nil, fmt.Errorf("empty list")
+	}
+
+	result := initial
+	for _, element := range elements {
+		value, err := toDecimal(element, s.precision)
+		if err != nil {
+			return nil, err
+		}
+		if !value.IsInteger() {
+			return nil, fmt.Errorf("expected integer, but got `%s`", element)
+		}
+		fold(result, new(big.Int).Abs(value.BigInt()))
+	}
+	return newInteger(result, s.exact), nil
+}
+
+// factorial returns product of integers from 1 to n
+func factorial(args []*big.Int) (*big.Int, error) {
+	n, err := smallInteger(args[0], "argument")
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).MulRange(1, n), nil
+}
+
+// permutations returns number of ordered selections of k items out of n
+func permutations(args []*big.Int) (*big.Int, error) {
+	n, k, err := selection(args)
+	if err != nil || k.Cmp(n) > 0 {
+		return big.NewInt(0), err
+	}
+
+	result := big.NewInt(1)
+	factor := new(big.Int).Set(n)
+	for i := k.Int64(); i > 0; i-- {
+		result.Mul(result, factor)
+		factor.Sub(factor, big.NewInt(1))
+	}
+	return result, nil
+}
+
+// combinations returns number of unordered selections of k items out of n, binomial coefficient
+func combinations(args []*big.Int) (*big.Int, error) {
+	n, k, err := selection(args)
+	if err != nil || k.Cmp(n) > 0 {
+		return big.NewInt(0), err
+	}
+
+	// C(n, k) = C(n, n - k), the smaller one needs fewer steps
+	if rest := new(big.Int).Sub(n, k); rest.Cmp(k) < 0 {
+		k = rest
+	}
+
+	result := big.NewInt(1)
+	factor := new(big.Int).Set(n)
+	for i := int64(1); i <= k.Int64(); i++ {
+		result.Mul(result, factor)
+		result.Quo(result, big.NewInt(i))
+		factor.Sub(factor, big.NewInt(1))
+	}
+	return result, nil
+}
+
+// selection validates number of items n and number of selected items k
+func selection(args []*big.Int) (n, k *big.Int, err error) {
+	n, k = args[0], args[1]
+	if n.Sign() < 0 {
+		return nil, nil, fmt.Errorf("the first argument must not be negative")
+	}
+	if _, err = smallInteger(k, "the second argument"); err != nil {
+		return nil, nil, err
+	}
+	return n, k, nil
+}
+
+// isPrime reports whether integer is a prime number, the test is exact for integers less than 2^64
+func isPrime(s *scope, args []Value) (Value, error) {
+	values, err := integerArgs(args, s.precision)
+	if err != nil {
+		return nil, err
+	}
+	return fromBool(values[0].ProbablyPrime(20)), nil
+}
+
+// nextPrime returns the smallest prime number greater than n
+func nextPrime(args []*big.Int) (*big.Int, error) {
+	if args[0].Cmp(big.NewInt(2)) < 0 {
+		return big.NewInt(2), nil
+	}
+
+	candidate := new(big.Int).Add(args[0], big.NewInt(1))
+	if candidate.Bit(0) == 0 {
+		candidate.Add(candidate, big.NewInt(1))
+	}
+	for !candidate.ProbablyPrime(20) {
+		candidate.Add(candidate, big.NewInt(2))
+	}
+	return candidate, nil
+}
+
+// factor returns list of prime factors of integer in ascending order, negative integers have factor -1
+func factor(s *scope, args []Value) (Value, error) {
+	values, err := integerArgs(args, s.precision)
+	if err != nil {
+		return nil, err
+	}
+
+	n := values[0]
+	if n.Sign() == 0 {
+		return nil, fmt.Errorf("zero can't be factored")
+	}
+
+	var factors []*big.Int
+	if n.Sign() < 0 {
+		factors = append(factors, big.NewInt(-1))
+	}
+
+	primes, err := primeFactors(new(big.Int).Abs(n))
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(primes, (*big.Int).Cmp)
+
+	elements := make([]Value, 0, len(factors)+len(primes))
+	for _, prime := range append(factors, primes...) {
+		elements = append(elements, newInteger(prime, s.exact))
+	}
+	return List{elements: elements}, nil
+}
+
+// primeFactors returns prime factors of positive integer in any order
+func primeFactors(n *big.Int) ([]*big.Int, error) {
+	var factors []*big.Int
+
+	remainder := new(big.Int)
+	for d := int64(2); d <= trialDivisionLimit; d++ {
+		divisor := big.NewInt(d)
+		if new(big.Int).Mul(divisor, divisor).Cmp(n) > 0 {
+			break
+		}
+		for {
+			quotient, _ := new(big.Int).QuoRem(n, divisor, remainder)
+			if remainder.Sign() != 0 {
+				break
+			}
+			factors = append(factors, divisor)
+			n = quotient
+		}
+	}
+
+	large := []*big.Int{n}
+	for len(large) != 0 {
+		n = large[len(large)-1]
+		large = large[:len(large)-1]
+
+		switch {
+		case n.Cmp(big.NewInt(1)) == 0:
+		case n.ProbablyPrime(20):
+			factors = append(factors, n)
+		default:
+			divisor := pollardRho(n)
+			if divisor == nil {
+				return nil, fmt.Errorf("can't factor `%s`, its factors are too large", n)
+			}
+			large = append(large, divisor, new(big.Int).Quo(n, divisor))
+		}
+	}
+	return factors, nil
+}
+
+// pollardRho returns non-trivial divisor of composite integer, or nil if it isn't found in reasonable time
+func pollardRho(n *big.Int) *big.Int {
+	next := func(x, c *big.Int) {
+		x.Mul(x, x)
+		x.Add(x, c)
+		x.Mod(x, n)
+	}
+
+	diff, divisor := new(big.Int), new(big.Int)
+	for c := int64(1); c <= 10; c++ {
+		increment := big.NewInt(c)
+		x, y := big.NewInt(2), big.NewInt(2)
+		for range maxRhoIterations {
+			next(x, increment)
+			next(y, increment)
+			next(y, increment)
+
+			divisor.GCD(nil, nil, diff.Abs(diff.Sub(x, y)), n)
+			if divisor.Cmp(big.NewInt(1)) != 0 {
+				break
+			}
+		}
+		if divisor.Cmp(big.NewInt(1)) != 0 && divisor.Cmp(n) != 0 {
+			return divisor
+		}
+	}
+	return nil
+}
+
+// modularPower returns base to the power modulo positive modulus, negative powers use modular inverse of the base
+func modularPower(args []*big.Int) (*big.Int, error) {
+	base, exponent, modulus := args[0], args[1], args[2]
+	if modulus.Sign() <= 0 {
+		return nil, fmt.Errorf("modulus must be positive")
+	}
+
+	result := new(big.Int).Exp(base, exponent, modulus)
+	if result == nil {
+		return nil, fmt.Errorf("%s has no inverse modulo %s", base, modulus)
+	}
+	return result, nil
+}
+
+// modularInverse returns x such that `a * x % m == 1`
+func modularInverse(args []*big.Int) (*big.Int, error) {
+	a, modulus := args[0], args[1]
+	if modulus.Sign() <= 0 {
+		return nil, fmt.Errorf("modulus must be positive")
+	}
+
+	result := new(big.Int).ModInverse(new(big.Int).Mod(a, modulus), modulus)
+	if result == nil {
+		return nil, fmt.Errorf("%s has no inverse modulo %s", a, modulus)
+	}
+	return result, nil
+}
+
+// fibonacci returns n-th Fibonacci number, `F(-n) = (-1)^(n+1) * F(n)`
+func fibonacci(args []*big.Int) (*big.Int, error) {
+	n, err := smallInteger(new(big.Int).Abs(args[0]), "absolute value of argument")
+	if err != nil {
+		return nil, err
+	}
+
+	// Fast doubling: F(2k) = F(k) * (2*F(k+1) - F(k)), F(2k+1) = F(k)^2 + F(k+1)^2
+	a, b := big.NewInt(0), big.NewInt(1)
+	for bit := 62; bit >= 0; bit-- {
+		c := new(big.Int).Lsh(b, 1)
+		c.Mul(a, c.Sub(c, a))
+		d := new(big.Int).Mul(a, a)
+		d.Add(d, new(big.Int).Mul(b, b))
+
+		if n>>bit&1 == 0 {
+			a, b = c, d
+		} else {
+			a, b = d, c.Add(c, d)
+		}
+	}
+
+	if args[0].Sign() < 0 && n%2 == 0 {
+		a.Neg(a)
+	}
+	return a, nil
+}
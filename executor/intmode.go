@@ -0,0 +1,93 @@
+package executor
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/shopspring/decimal"
+)
+
+// IntMode is a mode in which all values are treated as fixed width integers, results of operations are truncated
+// toward zero and wrapped around, so division is integer division like in C
+type IntMode struct {
+	Bits   uint // One of 8, 16, 32 or 64, zero disables integer mode
+	Signed bool
+}
+
+// ParseIntMode parses integer mode in format `i8`, `u16`, `i32`, `u64`, etc., `off` or empty string disables it
+func ParseIntMode(text string) (IntMode, error) {
+	if text == "" || text == "off" {
+		return IntMode{}, nil
+	}
+
+	mode := IntMode{}
+	switch text[0] {
+	case 'i':
+		mode.Signed = true
+	case 'u':
+		mode.Signed = false
+	default:
+		return IntMode{}, fmt.Errorf("invalid integer mode `%s`, expected `i` or `u` followed by bits", text)
+	}
+
+	bits, err := strconv.ParseUint(text[1:], 10, 8)
+	if err != nil {
+		return IntMode{}, fmt.Errorf("invalid integer mode `%s`, expected `i` or `u` followed by bits", text)
+	}
+	mode.Bits = uint(bits)
+
+	if err = mode.validate(); err != nil {
+		return IntMode{}, err
+	}
+	return mode, nil
+}
+
+// Enabled reports whether integer mode is enabled
+func (m IntMode) Enabled() bool {
+	return m.Bits != 0
+}
+
+func (m IntMode) String() string {
+	if !m.Enabled() {
+		return "off"
+	}
+	if m.Signed {
+		return "i" + strconv.FormatUint(uint64(m.Bits), 10)
+	}
+	return "u" + strconv.FormatUint(uint64(m.Bits), 10)
+}
+
+func (m IntMode) validate() error {
+	switch m.Bits {
+	case 0, 8, 16, 32, 64:
+		return nil
+	default:
+		return fmt.Errorf("unsupported integer size %d, expected 8, 16, 32 or 64", m.Bits)
+	}
+}
+
+// wrap truncates value toward zero and wraps it around to fit into the integer size
+func (m IntMode) wrap(value decimal.Decimal) decimal.Decimal {
+	if !m.Enabled() {
+		return value
+	}
+
+	modulo := new(big.Int).Lsh(big.NewInt(1), m.Bits)
+	integer := value.Truncate(0).BigInt()
+	integer.Mod(integer, modulo)
+
+	if m.Signed && integer.Cmp(new(big.Int).Rsh(modulo, 1)) >= 0 {
+		integer.Sub(integer, modulo)
+	}
+
+	return decimal.NewFromBigInt(integer, 0)
+}
+
+// twosComplement returns unsigned representation of negative value
+func (m IntMode) twosComplement(value decimal.Decimal) decimal.Decimal {
+	if !m.Enabled() || !value.IsNegative() {
+		return value
+	}
+	return value.Add(decimal.NewFromBigInt(new(big.Int).Lsh(big.NewInt(1), m.Bits), 0))
+}
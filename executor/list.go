@@ -0,0 +1,327 @@
+package executor
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// maxListLength is the maximal number of elements in ranges
+const maxListLength = 1 << 20
+
+// List is an ordered list of values, for example: `[1, 2, 3]` or `1..10`
+type List struct {
+	elements []Value
+}
+
+// NewList creates new list from elements
+func NewList(elements ...Value) List {
+	return List{elements: slices.Clone(elements)}
+}
+
+// Elements returns copy of list elements
+func (l List) Elements() []Value {
+	return slices.Clone(l.elements)
+}
+
+// Len returns number of elements in the list
+func (l List) Len() int {
+	return len(l.elements)
+}
+
+func (l List) String() string {
+	return formatList(l, ", ", Value.String)
+}
+
+func (l List) isValue() {}
+
+// isList reports whether any of values is a list
+func isList(values ...Value) bool {
+	for _, v := range values {
+		if _, ok := v.(List); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func toList(v Value) (List, error) {
+	list, ok := v.(List)
+	if !ok {
+		return List{}, fmt.Errorf("expected list, but got `%s`", v)
+	}
+	return list, nil
+}
+
+// mapElements applies function to the value, lists are processed element by element
+func mapElements(v Value, apply func(v Value) Value) Value {
+	list, ok := v.(List)
+	if !ok {
+		return apply(v)
+	}
+
+	elements := make([]Value, len(list.elements))
+	for i, element := range list.elements {
+		elements[i] = mapElements(element, apply)
+	}
+	return List{elements: elements}
+}
+
+// applyElementWise applies function to elements of lists with the same length, arguments that are not lists are used
+// with every element, for example: `[1, 2] + [3, 4]` or `[1, 2] * 3`
+func applyElementWise(
+	apply func(s *scope, args []Value) (Value, error),
+) func(s *scope, args []Value) (Value, error) {
+	var elementWise func(s *scope, args []Value) (Value, error)
+	elementWise = func(s *scope, args []Value) (Value, error) {
+		length := -1
+		for _, arg := range args {
+			list, ok := arg.(List)
+			if !ok {
+				continue
+			}
+			if length >= 0 && len(list.elements) != length {
+				return nil, fmt.Errorf("list lengths %d and %d don't match", length, len(list.elements))
+			}
+			length = len(list.elements)
+		}
+		if length < 0 {
+			return apply(s, args)
+		}
+
+		elements := make([]Value, length)
+		for i := range elements {
+			elementArgs := make([]Value, len(args))
+			for j, arg := range args {
+				if list, ok := arg.(List); ok {
+					elementArgs[j] = list.elements[i]
+				} else {
+					elementArgs[j] = arg
+				}
+			}
+
+			var err error
+			elements[i], err = elementWise(s, elementArgs)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return List{elements: elements}, nil
+	}
+	return elementWise
+}
+
+// rangeList returns list of integers from start to end inclusive, for example: `1..3` is `[1, 2, 3]`
+func rangeList(v1, v2 Value, precision int32) (Value, error) {
+	start, err := toDecimal(v1, precision)
+	if err != nil {
+		return nil, err
+	}
+	end, err := toDecimal(v2, precision)
+	if err != nil {
+		return nil, err
+	}
+	if !start.IsInteger() || !end.IsInteger() {
+		return nil, fmt.Errorf("range bounds must be integers")
+	}
+
+	length := end.Sub(start).Abs()
+	if length.GreaterThanOrEqual(decimal.NewFromInt(maxListLength)) {
+		return nil, fmt.Errorf("range is too large")
+	}
+
+	step := decimal.NewFromInt(1)
+	if start.GreaterThan(end) {
+		step = step.Neg()
+	}
+	_, _, exact := rationals(v1, v2)
+
+	elements := make([]Value, length.IntPart()+1)
+	for i := range elements {
+		if exact {
+			elements[i] = newRational(start)
+		} else {
+			elements[i] = NewNumber(start)
+		}
+		start = start.Add(step)
+	}
+	return List{elements: elements}, nil
+}
+
+// index returns element of the list, negative indexes are counted from the end of the list
+func index(v, i Value, precision int32) (Value, error) {
+	list, err := toList(v)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := toDecimal(i, precision)
+	if err != nil {
+		return nil, err
+	}
+	if !value.IsInteger() {
+		return nil, fmt.Errorf("index must be an integer")
+	}
+
+	length := decimal.NewFromInt(int64(len(list.elements)))
+	if value.IsNegative() {
+		value = value.Add(length)
+	}
+	if value.IsNegative() || value.GreaterThanOrEqual(length) {
+		return nil, fmt.Errorf("index %s out of range for list of length %s", i, length)
+	}
+	return list.elements[value.IntPart()], nil
+}
+
+// applyListArg applies function to elements of the list passed as the only argument
+func applyListArg(apply func(elements []Value) (Value, error)) func(s *scope, args []Value) (Value, error) {
+	return func(_ *scope, args []Value) (Value, error) {
+		list, err := toList(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return apply(list.elements)
+	}
+}
+
+var (
+	addElements = applyElementWise(applyValueOp(add))
+	divElements = applyElementWise(applyValueOp(div))
+)
+
+func sum(s *scope, elements []Value) (Value, error) {
+	if len(elements) == 0 {
+		return NewNumber(decimal.Zero), nil
+	}
+
+	result := elements[0]
+	for _, element := range elements[1:] {
+		var err error
+		result, err = addElements(s, []Value{result, element})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func mean(s *scope, elements []Value) (Value, error) {
+	if len(elements) == 0 {
+		return nil, fmt.Errorf("empty list")
+	}
+
+	total, err := sum(s, elements)
+	if err != nil {
+		return nil, err
+	}
+	return divElements(s, []Value{total, count(len(elements), elements[0])})
+}
+
+func median(s *scope, elements []Value) (Value, error) {
+	if len(elements) == 0 {
+		return nil, fmt.Errorf("empty list")
+	}
+
+	var err error
+	sorted := slices.Clone(elements)
+	slices.SortStableFunc(sorted, func(a, b Value) int {
+		c, compareErr := compare(a, b, s.precision)
+		if compareErr != nil {
+			err = compareErr
+		}
+		return c
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return sorted[middle], nil
+	}
+	return mean(s, sorted[middle-1:middle+1])
+}
+
+// stddev returns sample standard deviation
+func stddev(s *scope, elements []Value) (Value, error) {
+	if len(elements) < 2 {
+		return nil, fmt.Errorf("at least two elements expected")
+	}
+	for _, element := range elements {
+		switch element.(type) {
+		case List, Complex:
+			return nil, fmt.Errorf("expected real number or quantity, but got `%s`", element)
+		}
+	}
+
+	average, err := mean(s, elements)
+	if err != nil {
+		return nil, err
+	}
+
+	var variance Value
+	for i, element := range elements {
+		deviation, err := sub(element, average, s.precision)
+		if err != nil {
+			return nil, err
+		}
+		square, _ := mul(deviation, deviation, s.precision)
+		if i == 0 {
+			variance = square
+		} else if variance, err = add(variance, square, s.precision); err != nil {
+			return nil, err
+		}
+	}
+	variance, err = div(variance, count(len(elements)-1, variance), s.precision)
+	if err != nil {
+		return nil, err
+	}
+
+	// Variance has squared unit of elements
+	value, _ := magnitude(variance, s.precision)
+	_, unit := magnitude(average, s.precision)
+	root, err := squareRoot(value, s.precision)
+	if err != nil {
+		return nil, err
+	}
+	return newQuantity(root, unit, s.precision), nil
+}
+
+// extremum returns the smallest element if sign is negative, or the largest one if it is positive
+func extremum(elements []Value, sign int, precision int32) (Value, error) {
+	if len(elements) == 0 {
+		return nil, fmt.Errorf("empty list")
+	}
+
+	result := elements[0]
+	for _, element := range elements[1:] {
+		c, err := compare(element, result, precision)
+		if err != nil {
+			return nil, err
+		}
+		if c*sign > 0 {
+			result = element
+		}
+	}
+	return result, nil
+}
+
+// count returns number of elements as a value of the same kind as like, rationals stay exact
+func count(n int, like Value) Value {
+	value := decimal.NewFromInt(int64(n))
+	if _, ok := like.(Rational); ok {
+		return newRational(value)
+	}
+	return NewNumber(value)
+}
+
+// formatList formats list as `[1, 2, 3]` with elements joined by the separator
+func formatList(list List, separator string, format func(value Value) string) string {
+	elements := make([]string, len(list.elements))
+	for i, element := range list.elements {
+		elements[i] = format(element)
+	}
+	return "[" + strings.Join(elements, separator) + "]"
+}
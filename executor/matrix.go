@@ -0,0 +1,428 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+var errSingular = errors.New("matrix is singular")
+
+// matrix is a list of rows with the same number of elements, for example: `[[1, 2], [3, 4]]`
+type matrix [][]Value
+
+// toMatrix converts list of lists with the same non-zero length into the matrix, elements must not be lists
+func toMatrix(v Value) (matrix, bool) {
+	list, ok := v.(List)
+	if !ok || len(list.elements) == 0 {
+		return nil, false
+	}
+
+	m := make(matrix, len(list.elements))
+	for i, element := range list.elements {
+		row, ok := toVector(element)
+		if !ok || i > 0 && len(row) != len(m[0]) {
+			return nil, false
+		}
+		m[i] = row
+	}
+	return m, true
+}
+
+// toVector returns elements of the list if none of them is a list
+func toVector(v Value) ([]Value, bool) {
+	list, ok := v.(List)
+	if !ok || len(list.elements) == 0 || isList(list.elements...) {
+		return nil, false
+	}
+	return list.elements, true
+}
+
+func expectMatrix(v Value) (matrix, error) {
+	m, ok := toMatrix(v)
+	if !ok {
+		return nil, fmt.Errorf("expected matrix, but got `%s`", v)
+	}
+	return m, nil
+}
+
+func expectSquare(v Value) (matrix, error) {
+	m, err := expectMatrix(v)
+	if err != nil {
+		return nil, err
+	}
+	if m.rows() != m.cols() {
+		return nil, fmt.Errorf("expected square matrix, but got %s matrix", m.size())
+	}
+	return m, nil
+}
+
+func (m matrix) rows() int {
+	return len(m)
+}
+
+func (m matrix) cols() int {
+	return len(m[0])
+}
+
+func (m matrix) size() string {
+	return fmt.Sprintf("%dx%d", m.rows(), m.cols())
+}
+
+func (m matrix) value() Value {
+	rows := make([]Value, len(m))
+	for i, row := range m {
+		rows[i] = List{elements: row}
+	}
+	return List{elements: rows}
+}
+
+func (m matrix) clone() matrix {
+	c := make(matrix, len(m))
+	for i, row := range m {
+		c[i] = append([]Value(nil), row...)
+	}
+	return c
+}
+
+func (m matrix) transpose() matrix {
+	t := make(matrix, m.cols())
+	for j := range t {
+		t[j] = make([]Value, m.rows())
+		for i := range m {
+			t[j][i] = m[i][j]
+		}
+	}
+	return t
+}
+
+func (m matrix) mul(other matrix, precision int32) (matrix, error) {
+	if m.cols() != other.rows() {
+		return nil, fmt.Errorf("can't multiply %s matrix by %s matrix", m.size(), other.size())
+	}
+
+	columns := other.transpose()
+	result := make(matrix, m.rows())
+	for i := range result {
+		result[i] = make([]Value, other.cols())
+		for j := range result[i] {
+			var err error
+			result[i][j], err = dot(m[i], columns[j], precision)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
+
+func dot(v1, v2 []Value, precision int32) (Value, error) {
+	var result Value
+	for i := range v1 {
+		product, err := mul(v1[i], v2[i], precision)
+		if err != nil {
+			return nil, err
+		}
+
+		if i == 0 {
+			result = product
+		} else if result, err = add(result, product, precision); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// product multiplies matrices, matrix and vector by linear algebra rules, other values are multiplied element by
+// element
+func product(s *scope, args []Value) (Value, error) {
+	v1, v2 := args[0], args[1]
+	m1, isMatrix1 := toMatrix(v1)
+	m2, isMatrix2 := toMatrix(v2)
+	switch {
+	case isMatrix1 && isMatrix2:
+		result, err := m1.mul(m2, s.precision)
+		if err != nil {
+			return nil, err
+		}
+		return result.value(), nil
+	case isMatrix1:
+		if vector, ok := toVector(v2); ok {
+			// Matrix by column vector
+			result, err := m1.mul(matrix{vector}.transpose(), s.precision)
+			if err != nil {
+				return nil, err
+			}
+			return List{elements: result.transpose()[0]}, nil
+		}
+	case isMatrix2:
+		if vector, ok := toVector(v1); ok {
+			// Row vector by matrix
+			result, err := matrix{vector}.mul(m2, s.precision)
+			if err != nil {
+				return nil, err
+			}
+			return List{elements: result[0]}, nil
+		}
+	}
+
+	return applyElementWise(applyValueOp(mul))(s, args)
+}
+
+// matrixPower raises square matrix to the integer power as in linear algebra, other values are raised element
+// by element
+func matrixPower(s *scope, args []Value) (Value, error) {
+	m, ok := toMatrix(args[0])
+	if !ok {
+		return applyElementWise(raise)(s, args)
+	}
+	if _, ok = args[1].(List); ok {
+		return nil, fmt.Errorf("matrix can't be raised to the power of list")
+	}
+
+	exponent, err := toDecimal(args[1], s.precision)
+	if err != nil {
+		return nil, err
+	}
+	if !exponent.IsInteger() {
+		return nil, fmt.Errorf("matrix can be raised only to the integer power")
+	}
+	if exponent.Abs().GreaterThan(decimal.NewFromInt(maxIntegerArgument)) {
+		return nil, fmt.Errorf("exponent of matrix must not be greater than %d by absolute value", maxIntegerArgument)
+	}
+	if _, err = expectSquare(args[0]); err != nil {
+		return nil, err
+	}
+
+	if exponent.IsNegative() {
+		inverted, err := inverse(args[0], s.precision)
+		if err != nil {
+			return nil, err
+		}
+		m, _ = toMatrix(inverted)
+	}
+
+	// Exponentiation by squaring
+	result := identityMatrix(m.rows(), m[0][0])
+	for n := exponent.Abs().IntPart(); n > 0; n >>= 1 {
+		if n&1 == 1 {
+			if result, err = result.mul(m, s.precision); err != nil {
+				return nil, err
+			}
+		}
+		if n > 1 {
+			if m, err = m.mul(m, s.precision); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result.value(), nil
+}
+
+// pivotEpsilon returns the value below which pivots are considered to be zero
+func pivotEpsilon(precision int32) decimal.Decimal {
+	return decimal.New(1, -(precision - 4))
+}
+
+func pivotMagnitude(v Value, precision int32) decimal.Decimal {
+	if c, ok := v.(Complex); ok {
+		abs, _ := c.abs(precision)
+		return abs
+	}
+	value, _ := magnitude(v, precision)
+	return value.Abs()
+}
+
+// eliminate transforms the matrix into row echelon form using Gaussian elimination with partial pivoting, only the
+// first columns columns are used as pivots, sign of the determinant is returned, singular matrices result in error
+func (m matrix) eliminate(columns int, precision int32) (matrix, int, error) {
+	m = m.clone()
+	sign := 1
+	for col := range columns {
+		pivot := col
+		for row := col + 1; row < m.rows(); row++ {
+			if pivotMagnitude(m[row][col], precision).GreaterThan(pivotMagnitude(m[pivot][col], precision)) {
+				pivot = row
+			}
+		}
+		if pivotMagnitude(m[pivot][col], precision).LessThan(pivotEpsilon(precision)) {
+			return nil, 0, errSingular
+		}
+		if pivot != col {
+			m[pivot], m[col] = m[col], m[pivot]
+			sign = -sign
+		}
+
+		for row := col + 1; row < m.rows(); row++ {
+			factor, err := div(m[row][col], m[col][col], precision)
+			if err != nil {
+				return nil, 0, err
+			}
+			for j := col; j < m.cols(); j++ {
+				scaled, err := mul(factor, m[col][j], precision)
+				if err != nil {
+					return nil, 0, err
+				}
+				if m[row][j], err = sub(m[row][j], scaled, precision); err != nil {
+					return nil, 0, err
+				}
+			}
+		}
+	}
+	return m, sign, nil
+}
+
+// substitute solves upper triangular system in the first columns of the matrix, solution for each of the remaining
+// columns is returned in rows
+func (m matrix) substitute(columns int, precision int32) (matrix, error) {
+	solution := make(matrix, m.rows())
+	for i := m.rows() - 1; i >= 0; i-- {
+		solution[i] = make([]Value, m.cols()-columns)
+		for k := range solution[i] {
+			value := m[i][columns+k]
+			for j := i + 1; j < columns; j++ {
+				known, err := mul(m[i][j], solution[j][k], precision)
+				if err != nil {
+					return nil, err
+				}
+				if value, err = sub(value, known, precision); err != nil {
+					return nil, err
+				}
+			}
+
+			var err error
+			if solution[i][k], err = div(value, m[i][i], precision); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return solution, nil
+}
+
+// augment appends columns of other matrix to the matrix
+func (m matrix) augment(other matrix) matrix {
+	result := make(matrix, m.rows())
+	for i := range result {
+		result[i] = append(append([]Value(nil), m[i]...), other[i]...)
+	}
+	return result
+}
+
+func determinant(v Value, precision int32) (Value, error) {
+	m, err := expectSquare(v)
+	if err != nil {
+		return nil, err
+	}
+
+	echelon, sign, err := m.eliminate(m.cols(), precision)
+	if errors.Is(err, errSingular) {
+		return count(0, m[0][0]), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := echelon[0][0]
+	for i := 1; i < echelon.rows(); i++ {
+		if result, err = mul(result, echelon[i][i], precision); err != nil {
+			return nil, err
+		}
+	}
+	if sign < 0 {
+		result = neg(result)
+	}
+	return result, nil
+}
+
+func inverse(v Value, precision int32) (Value, error) {
+	m, err := expectSquare(v)
+	if err != nil {
+		return nil, err
+	}
+
+	echelon, _, err := m.augment(identityMatrix(m.rows(), m[0][0])).eliminate(m.cols(), precision)
+	if err != nil {
+		return nil, err
+	}
+	result, err := echelon.substitute(m.cols(), precision)
+	if err != nil {
+		return nil, err
+	}
+	return result.value(), nil
+}
+
+// solve solves system of linear equations `A * x = b`, b is a vector or a matrix with the same number of rows as A
+func solve(a, b Value, precision int32) (Value, error) {
+	m, err := expectSquare(a)
+	if err != nil {
+		return nil, err
+	}
+
+	right, isMatrix := toMatrix(b)
+	if !isMatrix {
+		vector, ok := toVector(b)
+		if !ok {
+			return nil, fmt.Errorf("expected vector or matrix, but got `%s`", b)
+		}
+		right = matrix{vector}.transpose()
+	}
+	if right.rows() != m.rows() {
+		return nil, fmt.Errorf("can't solve system with %s matrix and %d values", m.size(), right.rows())
+	}
+
+	echelon, _, err := m.augment(right).eliminate(m.cols(), precision)
+	if err != nil {
+		return nil, err
+	}
+	solution, err := echelon.substitute(m.cols(), precision)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isMatrix {
+		return List{elements: solution.transpose()[0]}, nil
+	}
+	return solution.value(), nil
+}
+
+// identityMatrix returns identity matrix of the size, elements are values of the same kind as like
+func identityMatrix(size int, like Value) matrix {
+	m := make(matrix, size)
+	for i := range m {
+		m[i] = make([]Value, size)
+		for j := range m[i] {
+			if i == j {
+				m[i][j] = count(1, like)
+			} else {
+				m[i][j] = count(0, like)
+			}
+		}
+	}
+	return m
+}
+
+func identity(v Value, precision int32) (Value, error) {
+	size, err := toDecimal(v, precision)
+	if err != nil {
+		return nil, err
+	}
+	if !size.IsInteger() || !size.IsPositive() {
+		return nil, fmt.Errorf("size must be a positive integer")
+	}
+	if size.Mul(size).GreaterThan(decimal.NewFromInt(maxListLength)) {
+		return nil, fmt.Errorf("size is too large")
+	}
+	return identityMatrix(int(size.IntPart()), v).value(), nil
+}
+
+// applyMatrixArg applies function to the matrix passed as the only argument
+func applyMatrixArg(apply func(m matrix) (Value, error)) func(s *scope, args []Value) (Value, error) {
+	return func(_ *scope, args []Value) (Value, error) {
+		m, err := expectMatrix(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return apply(m)
+	}
+}
@@ -0,0 +1,177 @@
+package executor
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/mymmrac/mm/utils"
+)
+
+var basePrefixes = map[string]int{
+	"0x": 16,
+	"0X": 16,
+	"0b": 2,
+	"0B": 2,
+	"0o": 8,
+	"0O": 8,
+}
+
+var baseNames = map[int]string{
+	2:  "binary",
+	8:  "octal",
+	10: "decimal",
+	16: "hexadecimal",
+}
+
+// scanNumber returns end of number literal that starts at i
+func scanNumber(expression string, i int) int {
+	if i+2 <= len(expression) && basePrefixes[expression[i:i+2]] != 0 {
+		j := i + 2
+		for j < len(expression) && (utils.IsLetter(expression[j]) || utils.IsDigit(expression[j])) {
+			j++
+		}
+		return j
+	}
+
+	j := i + 1
+	hasDot := false
+
+numberLoop:
+	for j < len(expression) {
+		switch {
+		case utils.IsDigit(expression[j]) || expression[j] == '_':
+			j++
+		case !hasDot && expression[j] == '.' && !strings.HasPrefix(expression[j:], ".."): // Range `1..10`
+			hasDot = true
+			j++
+		default:
+			break numberLoop
+		}
+	}
+
+	// Exponent is a part of the number only if it has digits
+	if j < len(expression) && utils.IsInCharset(expression[j], "eE") {
+		k := j + 1
+		if k < len(expression) && utils.IsInCharset(expression[k], "+-") {
+			k++
+		}
+		if k < len(expression) && utils.IsDigit(expression[k]) {
+			for k < len(expression) && (utils.IsDigit(expression[k]) || expression[k] == '_') {
+				k++
+			}
+			j = k
+		}
+	}
+
+	return j
+}
+
+// parseNumber parses decimal, hexadecimal (`0x`), binary (`0b`) or octal (`0o`) number, digits can be separated by
+// `_`
+func parseNumber(text string) (decimal.Decimal, error) {
+	base := 10
+	if len(text) > 2 && basePrefixes[text[:2]] != 0 {
+		base = basePrefixes[text[:2]]
+		text = text[2:]
+	}
+
+	for i := 0; i < len(text); i++ {
+		if text[i] == '_' && (i == 0 || i == len(text)-1 || !isDigit(text[i-1], base) || !isDigit(text[i+1], base)) {
+			return decimal.Zero, fmt.Errorf("separator `_` must be between digits")
+		}
+	}
+	text = strings.ReplaceAll(text, "_", "")
+
+	if base == 10 {
+		return decimal.NewFromString(text)
+	}
+
+	value, ok := new(big.Int).SetString(text, base)
+	if !ok {
+		return decimal.Zero, fmt.Errorf("invalid %s number", baseNames[base])
+	}
+	return decimal.NewFromBigInt(value, 0), nil
+}
+
+func isDigit(c byte, base int) bool {
+	switch {
+	case utils.IsDigit(c):
+		return int(c-'0') < base
+	case c >= 'a' && c <= 'z':
+		return int(c-'a')+10 < base
+	case c >= 'A' && c <= 'Z':
+		return int(c-'A')+10 < base
+	default:
+		return false
+	}
+}
+
+// formatNumber formats value rounded to the precision digits after the point in the base, decimal numbers are
+// formatted in the format
+func formatNumber(value decimal.Decimal, precision int32, base int, format Format) string {
+	if base == 10 {
+		return format.formatDecimal(value, precision)
+	}
+
+	prefix := ""
+	if value.IsNegative() {
+		prefix = "-"
+		value = value.Neg()
+	}
+
+	switch base {
+	case 2:
+		prefix += "0b"
+	case 8:
+		prefix += "0o"
+	case 16:
+		prefix += "0x"
+	}
+
+	if precision < 0 {
+		precision = 0
+	}
+
+	bigBase := big.NewInt(int64(base))
+	scale := new(big.Int).Exp(bigBase, big.NewInt(int64(precision)), nil)
+	scaled := value.Mul(decimal.NewFromBigInt(scale, 0)).Round(0).BigInt()
+
+	integer, fraction := new(big.Int).QuoRem(scaled, scale, new(big.Int))
+	if prefix[0] == '-' && scaled.Sign() == 0 {
+		prefix = prefix[1:]
+	}
+
+	s := prefix + strings.ToUpper(integer.Text(base))
+	if fraction.Sign() == 0 {
+		return s
+	}
+
+	fractionDigits := strings.ToUpper(fraction.Text(base))
+	fractionDigits = strings.Repeat("0", int(precision)-len(fractionDigits)) + fractionDigits
+	return s + "." + strings.TrimRight(fractionDigits, "0")
+}
+
+// formatValue formats value rounded to the precision digits after the point in the base, rationals are formatted as
+// fractions or mixed numbers depending on exact mode
+func formatValue(value Value, precision int32, base int, exact ExactMode, format Format) string {
+	switch value := value.(type) {
+	case Rational:
+		return formatRational(value.value, base, exact == ExactMixed, format)
+	case Quantity:
+		return formatQuantity(formatNumber(value.value, precision, base, format), value.unit)
+	case Complex:
+		return formatComplex(value, func(number decimal.Decimal) string {
+			return formatNumber(number, precision, base, format)
+		})
+	case List:
+		return formatList(value, format.elementSeparator(), func(element Value) string {
+			return formatValue(element, precision, base, exact, format)
+		})
+	default:
+		number, _ := magnitude(value, workingPrecision(precision))
+		return formatNumber(number, precision, base, format)
+	}
+}
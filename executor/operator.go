@@ -2,6 +2,7 @@ package executor
 
 import (
 	"fmt"
+	"math/big"
 	"slices"
 
 	"github.com/shopspring/decimal"
@@ -9,124 +10,438 @@ import (
 	"github.com/mymmrac/mm/utils"
 )
 
-const defaultPrecision = 32
+const (
+	// defaultPrecision is the working precision of programs that are run without requested output precision
+	defaultPrecision = 32
+	// guardDigits are added to the requested precision, so that rounding errors don't reach printed digits
+	guardDigits = 16
+	// MaxPrecision is the maximal number of digits after the point results can be requested with
+	MaxPrecision = 1000
+)
+
+// workingPrecision returns precision of intermediate results for the requested number of digits after the point
+func workingPrecision(precision int32) int32 {
+	return max(precision, defaultPrecision-guardDigits) + guardDigits
+}
 
 type Operator struct {
 	text       string
 	name       string
 	precedence uint
 	arity      uint
-	apply      func(stack *utils.Stack[decimal.Decimal]) error
+	postfix    bool // Unary operator that is written after its operand, for example: `5!`
+	integer    bool // Operands must be integers, in integer mode they are checked before they are truncated
+	apply      func(s *scope, args []Value) (Value, error)
+
+	// lazy is used instead of apply by operators that evaluate their operands on demand
+	lazy func(args []func() (Value, error)) (Value, error)
 }
 
+var (
+	truth = NewNumber(decimal.NewFromInt(1))
+	lie   = NewNumber(decimal.Zero)
+)
+
 var (
 	opOpenParenthesis  = Operator{text: "(", name: "open parenthesis"}
 	opCloseParenthesis = Operator{text: ")", name: "close parenthesis"}
 	opComma            = Operator{text: ",", name: "comma"}
+	opAssign           = Operator{text: "=", name: "assignment"}
+	opOpenBracket      = Operator{text: "[", name: "open bracket"}
+	opCloseBracket     = Operator{text: "]", name: "close bracket"}
+)
+
+var (
+	opPercent = Operator{
+		text:       "%",
+		name:       "percent",
+		precedence: 15,
+		arity:      1,
+		postfix:    true,
+		apply:      applyElementWise(percent),
+	}
+	opPercentOf = Operator{
+		text:       "of",
+		name:       "percent of",
+		precedence: 12,
+		arity:      2,
+		apply:      applyElementWise(applyValueOp(mul)),
+	}
+
+	// Addition and subtraction of percent are not known operators, parser uses them instead of `+` and `-` when the
+	// right operand is percent, for example: `200 + 15%` is `230`
+	opAddPercent = Operator{
+		text:       "+",
+		name:       "percent addition",
+		precedence: 11,
+		arity:      2,
+		apply:      applyElementWise(applyPercentOp(add)),
+	}
+	opSubPercent = Operator{
+		text:       "-",
+		name:       "percent subtraction",
+		precedence: 11,
+		arity:      2,
+		apply:      applyElementWise(applyPercentOp(sub)),
+	}
 )
 
 var knownOperators = []Operator{
 	opOpenParenthesis,
 	opCloseParenthesis,
 	opComma,
+	opAssign,
+	opOpenBracket,
+	opCloseBracket,
+	opPercent,
+	opPercentOf,
 
 	{
 		text:       "+",
 		name:       "addition",
-		precedence: 1,
+		precedence: 11,
 		arity:      2,
-		apply: applyBinaryOp(func(v1, v2 decimal.Decimal) (decimal.Decimal, error) {
-			return v1.Add(v2), nil
-		}),
+		apply:      applyElementWise(applyValueOp(add)),
 	},
 	{
 		text:       "+",
 		name:       "unary plus",
-		precedence: 4,
+		precedence: 14,
 		arity:      1,
-		apply: applyUnaryOp(func(v1 decimal.Decimal) (decimal.Decimal, error) {
-			return v1, nil
-		}),
+		apply: func(_ *scope, args []Value) (Value, error) {
+			return args[0], nil
+		},
 	},
 	{
 		text:       "-",
 		name:       "subtraction",
-		precedence: 1,
+		precedence: 11,
 		arity:      2,
-		apply: applyBinaryOp(func(v1, v2 decimal.Decimal) (decimal.Decimal, error) {
-			return v1.Sub(v2), nil
-		}),
+		apply:      applyElementWise(applyValueOp(sub)),
 	},
 	{
 		text:       "-",
 		name:       "unary minus",
-		precedence: 4,
+		precedence: 14,
 		arity:      1,
-		apply: applyUnaryOp(func(v1 decimal.Decimal) (decimal.Decimal, error) {
-			return v1.Neg(), nil
+		apply: applyElementWise(func(_ *scope, args []Value) (Value, error) {
+			return neg(args[0]), nil
 		}),
 	},
 	{
 		text:       "*",
 		name:       "multiplication",
-		precedence: 2,
+		precedence: 12,
 		arity:      2,
-		apply: applyBinaryOp(func(v1, v2 decimal.Decimal) (decimal.Decimal, error) {
-			return v1.Mul(v2), nil
-		}),
+		apply:      product,
 	},
 	{
 		text:       "/",
 		name:       "division",
-		precedence: 2,
+		precedence: 12,
 		arity:      2,
-		apply: applyBinaryOp(func(v1, v2 decimal.Decimal) (decimal.Decimal, error) {
-			if v2.IsZero() {
-				return decimal.Zero, fmt.Errorf("division by zero")
-			}
-			return v1.DivRound(v2, defaultPrecision), nil
-		}),
+		apply:      applyElementWise(applyValueOp(div)),
 	},
 	{
 		text:       "//",
 		name:       "floor division",
-		precedence: 2,
+		precedence: 12,
 		arity:      2,
-		apply: applyBinaryOp(func(v1, v2 decimal.Decimal) (decimal.Decimal, error) {
+		apply: applyElementWise(applyBinaryOp(func(v1, v2 decimal.Decimal) (decimal.Decimal, error) {
 			if v2.IsZero() {
 				return decimal.Zero, fmt.Errorf("division by zero")
 			}
 			return v1.DivRound(v2, 0), nil
-		}),
+		})),
 	},
 	{
 		text:       "^",
 		name:       "power",
-		precedence: 3,
+		precedence: 13,
 		arity:      2,
-		apply: applyBinaryOp(func(v1, v2 decimal.Decimal) (decimal.Decimal, error) {
-			switch {
-			case v1.IsZero() && v2.IsZero():
-				return decimal.Zero, fmt.Errorf("undefined value (0 ^ 0)")
-			case v1.IsZero() && v2.IsNegative():
-				return decimal.Zero, fmt.Errorf("infinity")
-			case v1.IsNegative() && !v2.IsInteger():
-				return decimal.Zero, fmt.Errorf("imaginary value")
-			}
-			return v1.PowWithPrecision(v2, defaultPrecision)
-		}),
+		apply:      matrixPower,
+	},
+	{
+		text:       squareSign,
+		name:       "square",
+		precedence: 15,
+		arity:      1,
+		postfix:    true,
+		apply:      applyFixedPower(2),
+	},
+	{
+		text:       cubeSign,
+		name:       "cube",
+		precedence: 15,
+		arity:      1,
+		postfix:    true,
+		apply:      applyFixedPower(3),
+	},
+	{
+		text:       squareRootSign,
+		name:       "square root",
+		precedence: 14,
+		arity:      1,
+		apply:      applyElementWise(sqrt),
 	},
 	{
 		text:       "%",
 		name:       "modulo",
-		precedence: 2,
+		precedence: 12,
 		arity:      2,
-		apply: applyBinaryOp(func(v1, v2 decimal.Decimal) (decimal.Decimal, error) {
+		apply: applyElementWise(applyBinaryOp(func(v1, v2 decimal.Decimal) (decimal.Decimal, error) {
+			if v2.IsZero() {
+				return decimal.Zero, fmt.Errorf("division by zero")
+			}
 			return v1.Mod(v2), nil
+		})),
+	},
+	{
+		text:       "==",
+		name:       "equal",
+		precedence: 4,
+		arity:      2,
+		apply: applyEqualOp(func(eq bool) bool {
+			return eq
+		}),
+	},
+	{
+		text:       "!=",
+		name:       "not equal",
+		precedence: 4,
+		arity:      2,
+		apply: applyEqualOp(func(eq bool) bool {
+			return !eq
 		}),
 	},
+	{
+		text:       "<",
+		name:       "less",
+		precedence: 5,
+		arity:      2,
+		apply: applyCompareOp(func(c int) bool {
+			return c < 0
+		}),
+	},
+	{
+		text:       "<=",
+		name:       "less or equal",
+		precedence: 5,
+		arity:      2,
+		apply: applyCompareOp(func(c int) bool {
+			return c <= 0
+		}),
+	},
+	{
+		text:       ">",
+		name:       "greater",
+		precedence: 5,
+		arity:      2,
+		apply: applyCompareOp(func(c int) bool {
+			return c > 0
+		}),
+	},
+	{
+		text:       ">=",
+		name:       "greater or equal",
+		precedence: 5,
+		arity:      2,
+		apply: applyCompareOp(func(c int) bool {
+			return c >= 0
+		}),
+	},
+	{
+		text:       "&&",
+		name:       "logical and",
+		precedence: 3,
+		arity:      2,
+		lazy: func(args []func() (Value, error)) (Value, error) {
+			v1, err := isTrue(args[0]())
+			if err != nil || !v1 {
+				return lie, err
+			}
+
+			v2, err := isTrue(args[1]())
+			if err != nil {
+				return lie, err
+			}
+			return fromBool(v2), nil
+		},
+	},
+	{
+		text:       "||",
+		name:       "logical or",
+		precedence: 2,
+		arity:      2,
+		lazy: func(args []func() (Value, error)) (Value, error) {
+			v1, err := isTrue(args[0]())
+			if err != nil || v1 {
+				return truth, err
+			}
+
+			v2, err := isTrue(args[1]())
+			if err != nil {
+				return lie, err
+			}
+			return fromBool(v2), nil
+		},
+	},
+	{
+		text:       "!",
+		name:       "logical not",
+		precedence: 14,
+		arity:      1,
+		apply: func(_ *scope, args []Value) (Value, error) {
+			v1, err := isTrue(args[0], nil)
+			if err != nil {
+				return nil, err
+			}
+			return fromBool(!v1), nil
+		},
+	},
+	{
+		text:       "!",
+		name:       "factorial",
+		precedence: 15,
+		arity:      1,
+		postfix:    true,
+		apply:      applyElementWise(applyIntegerArgs(factorial)),
+	},
+	{
+		text:       "&",
+		name:       "bitwise and",
+		precedence: 9,
+		arity:      2,
+		integer:    true,
+		apply: applyBitwiseOp(func(v1, v2 *big.Int) (*big.Int, error) {
+			return v1.And(v1, v2), nil
+		}),
+	},
+	{
+		text:       "|",
+		name:       "bitwise or",
+		precedence: 7,
+		arity:      2,
+		integer:    true,
+		apply: applyBitwiseOp(func(v1, v2 *big.Int) (*big.Int, error) {
+			return v1.Or(v1, v2), nil
+		}),
+	},
+	{
+		text:       "xor",
+		name:       "bitwise exclusive or",
+		precedence: 8,
+		arity:      2,
+		integer:    true,
+		apply: applyBitwiseOp(func(v1, v2 *big.Int) (*big.Int, error) {
+			return v1.Xor(v1, v2), nil
+		}),
+	},
+	{
+		text:       "<<",
+		name:       "left shift",
+		precedence: 10,
+		arity:      2,
+		integer:    true,
+		apply: applyBitwiseOp(func(v1, v2 *big.Int) (*big.Int, error) {
+			shift, err := shiftCount(v2)
+			if err != nil {
+				return nil, err
+			}
+			return v1.Lsh(v1, shift), nil
+		}),
+	},
+	{
+		text:       ">>",
+		name:       "right shift",
+		precedence: 10,
+		arity:      2,
+		integer:    true,
+		apply: applyBitwiseOp(func(v1, v2 *big.Int) (*big.Int, error) {
+			shift, err := shiftCount(v2)
+			if err != nil {
+				return nil, err
+			}
+			return v1.Rsh(v1, shift), nil
+		}),
+	},
+	{
+		text:       "~",
+		name:       "bitwise not",
+		precedence: 14,
+		arity:      1,
+		integer:    true,
+		apply: applyElementWise(applyUnaryOp(func(v1 decimal.Decimal) (decimal.Decimal, error) {
+			if !v1.IsInteger() {
+				return decimal.Zero, fmt.Errorf("operand must be an integer")
+			}
+			return decimal.NewFromBigInt(new(big.Int).Not(v1.BigInt()), 0), nil
+		})),
+	},
+	{
+		text:       "to",
+		name:       "unit conversion",
+		precedence: 1,
+		arity:      2,
+		apply:      applyElementWise(applyValueOp(convertTo)),
+	},
+	{
+		text:       "in",
+		name:       "unit conversion",
+		precedence: 1,
+		arity:      2,
+		apply:      applyElementWise(applyValueOp(convertTo)),
+	},
+	{
+		text:       "..",
+		name:       "range",
+		precedence: 6,
+		arity:      2,
+		apply:      applyValueOp(rangeList),
+	},
+}
+
+const (
+	squareRootSign = "√"
+	squareSign     = "²"
+	cubeSign       = "³"
+)
+
+func lookupOperator(text string, arity uint) (*Operator, bool) {
+	opIndex := slices.IndexFunc(knownOperators, func(op Operator) bool {
+		return op.arity == arity && !op.postfix && op.text == text
+	})
+	if opIndex < 0 {
+		return nil, false
+	}
+	return &knownOperators[opIndex], true
+}
+
+// percentOperator returns operator that applies percent to the left operand instead of the binary operator
+func percentOperator(operator *Operator) (*Operator, bool) {
+	switch operator.text {
+	case opAddPercent.text:
+		return &opAddPercent, true
+	case opSubPercent.text:
+		return &opSubPercent, true
+	default:
+		return nil, false
+	}
+}
+
+func lookupPostfixOperator(text string) (*Operator, bool) {
+	opIndex := slices.IndexFunc(knownOperators, func(op Operator) bool {
+		return op.postfix && op.text == text
+	})
+	if opIndex < 0 {
+		return nil, false
+	}
+	return &knownOperators[opIndex], true
 }
 
+// maxShift is the maximal number of bits values can be shifted by
+const maxShift = 1 << 16
+
 var knownUniqueOperators []string
 
 var uniqueness = make(map[string]bool)
@@ -134,15 +449,22 @@ var uniqueness = make(map[string]bool)
 func init() {
 	for _, operator := range knownOperators {
 		if operator.text == opOpenParenthesis.text || operator.text == opCloseParenthesis.text ||
-			operator.text == opComma.text {
+			operator.text == opComma.text || operator.text == opAssign.text ||
+			operator.text == opOpenBracket.text || operator.text == opCloseBracket.text {
 			utils.Assert(operator.arity == 0, fmt.Sprintf("operator `%s` arity must be 0", operator.text))
 		} else {
 			utils.Assert(operator.arity == 1 || operator.arity == 2,
 				fmt.Sprintf("operator `%s` arity must be 1 or 2", operator.text),
 			)
 		}
+		utils.Assert(!operator.postfix || operator.arity == 1,
+			fmt.Sprintf("postfix operator `%s` arity must be 1", operator.text),
+		)
 
 		key := fmt.Sprintf("%s/%d", operator.text, operator.arity)
+		if operator.postfix {
+			key += "/postfix"
+		}
 		utils.Assert(!uniqueness[key], fmt.Sprintf("operator `%s` already exists", key))
 		uniqueness[key] = true
 
@@ -155,35 +477,186 @@ func init() {
 	knownUniqueOperators = slices.Compact(knownUniqueOperators)
 }
 
+// fromBool converts boolean into truth value
+func fromBool(b bool) Value {
+	if b {
+		return truth
+	}
+	return lie
+}
+
+// isTrue reports whether value is true, any non-zero number is considered true
+func isTrue(v Value, err error) (bool, error) {
+	if err != nil {
+		return false, err
+	}
+	if r, ok := v.(Rational); ok {
+		return r.value.Sign() != 0, nil
+	}
+
+	value, err := toDecimal(v, defaultPrecision)
+	if err != nil {
+		return false, err
+	}
+	return !value.IsZero(), nil
+}
+
+func shiftCount(v *big.Int) (uint, error) {
+	if v.Sign() < 0 {
+		return 0, fmt.Errorf("negative shift count")
+	}
+	if v.Cmp(big.NewInt(maxShift)) > 0 {
+		return 0, fmt.Errorf("shift count too large")
+	}
+	return uint(v.Uint64()), nil
+}
+
+// raise raises the first argument to the power of the second one, in complex mode powers of negative numbers are
+// complex
+func raise(s *scope, args []Value) (Value, error) {
+	if s.complex {
+		return powComplex(args[0], args[1], s.precision)
+	}
+	return pow(args[0], args[1], s.precision)
+}
+
+// applyFixedPower raises the argument to the exponent, for example: `x²`
+func applyFixedPower(exponent int64) func(s *scope, args []Value) (Value, error) {
+	return func(s *scope, args []Value) (Value, error) {
+		return matrixPower(s, []Value{args[0], newInteger(big.NewInt(exponent), s.exact)})
+	}
+}
+
+// applyDecimalArgs converts arguments into decimals with working precision, all arguments must be dimensionless
+func applyDecimalArgs(
+	apply func(args []decimal.Decimal, precision int32) (decimal.Decimal, error),
+) func(s *scope, args []Value) (Value, error) {
+	return func(s *scope, args []Value) (Value, error) {
+		values := make([]decimal.Decimal, len(args))
+		for i, arg := range args {
+			var err error
+			values[i], err = toDecimal(arg, s.precision)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		result, err := apply(values, s.precision)
+		if err != nil {
+			return nil, err
+		}
+		return NewNumber(result), nil
+	}
+}
+
 func applyUnaryOp(
 	apply func(v1 decimal.Decimal) (decimal.Decimal, error),
-) func(stack *utils.Stack[decimal.Decimal]) error {
-	return func(stack *utils.Stack[decimal.Decimal]) error {
-		v1 := stack.Pop()
+) func(s *scope, args []Value) (Value, error) {
+	return applyDecimalArgs(func(args []decimal.Decimal, _ int32) (decimal.Decimal, error) {
+		return apply(args[0])
+	})
+}
 
-		result, err := apply(v1)
+func applyBinaryOp(
+	apply func(v1, v2 decimal.Decimal) (decimal.Decimal, error),
+) func(s *scope, args []Value) (Value, error) {
+	return applyDecimalArgs(func(args []decimal.Decimal, _ int32) (decimal.Decimal, error) {
+		return apply(args[0], args[1])
+	})
+}
+
+// applyUnaryFunc applies function which result is calculated with working precision
+func applyUnaryFunc(
+	apply func(x decimal.Decimal, precision int32) (decimal.Decimal, error),
+) func(s *scope, args []Value) (Value, error) {
+	return applyDecimalArgs(func(args []decimal.Decimal, precision int32) (decimal.Decimal, error) {
+		return apply(args[0], precision)
+	})
+}
+
+// applyBinaryFunc applies function of two arguments which result is calculated with working precision
+func applyBinaryFunc(
+	apply func(x, y decimal.Decimal, precision int32) (decimal.Decimal, error),
+) func(s *scope, args []Value) (Value, error) {
+	return applyDecimalArgs(func(args []decimal.Decimal, precision int32) (decimal.Decimal, error) {
+		return apply(args[0], args[1], precision)
+	})
+}
+
+func applyValueOp(
+	apply func(v1, v2 Value, precision int32) (Value, error),
+) func(s *scope, args []Value) (Value, error) {
+	return func(s *scope, args []Value) (Value, error) {
+		return apply(args[0], args[1], s.precision)
+	}
+}
+
+func applyCompareOp(apply func(c int) bool) func(s *scope, args []Value) (Value, error) {
+	return func(s *scope, args []Value) (Value, error) {
+		c, err := compare(args[0], args[1], s.precision)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		stack.Push(result)
+		return fromBool(apply(c)), nil
+	}
+}
 
-		return nil
+func applyEqualOp(apply func(eq bool) bool) func(s *scope, args []Value) (Value, error) {
+	return func(s *scope, args []Value) (Value, error) {
+		eq, err := equal(args[0], args[1], s.precision)
+		if err != nil {
+			return nil, err
+		}
+		return fromBool(apply(eq)), nil
 	}
 }
 
-func applyBinaryOp(
-	apply func(v1, v2 decimal.Decimal) (decimal.Decimal, error),
-) func(stack *utils.Stack[decimal.Decimal]) error {
-	return func(stack *utils.Stack[decimal.Decimal]) error {
-		v2 := stack.Pop()
-		v1 := stack.Pop()
+// applyKeepUnit applies function to the magnitude of the first argument and keeps its unit in the result
+func applyKeepUnit(
+	apply func(s *scope, args []Value) (Value, error),
+) func(s *scope, args []Value) (Value, error) {
+	return func(s *scope, args []Value) (Value, error) {
+		quantity, ok := args[0].(Quantity)
+		if !ok {
+			return apply(s, args)
+		}
 
-		result, err := apply(v1, v2)
+		args = slices.Clone(args)
+		args[0] = NewNumber(quantity.value)
+
+		result, err := apply(s, args)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		stack.Push(result)
+		return newQuantity(result.(Number).value, quantity.unit, s.precision), nil
+	}
+}
 
-		return nil
+// applyPercentOp applies operator to the first argument and the percent of it, percent is already divided by 100
+func applyPercentOp(
+	apply func(v1, v2 Value, precision int32) (Value, error),
+) func(s *scope, args []Value) (Value, error) {
+	return func(s *scope, args []Value) (Value, error) {
+		part, err := mul(args[0], args[1], s.precision)
+		if err != nil {
+			return nil, err
+		}
+		return apply(args[0], part, s.precision)
 	}
 }
+
+func applyBitwiseOp(
+	apply func(v1, v2 *big.Int) (*big.Int, error),
+) func(s *scope, args []Value) (Value, error) {
+	return applyElementWise(applyBinaryOp(func(v1, v2 decimal.Decimal) (decimal.Decimal, error) {
+		if !v1.IsInteger() || !v2.IsInteger() {
+			return decimal.Zero, fmt.Errorf("operands must be integers")
+		}
+
+		result, err := apply(v1.BigInt(), v2.BigInt())
+		if err != nil {
+			return decimal.Zero, err
+		}
+		return decimal.NewFromBigInt(result, 0), nil
+	}))
+}
@@ -0,0 +1,621 @@
+package executor
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mymmrac/mm/utils"
+)
+
+// parser builds abstract syntax tree from tokens and resolves identifiers
+type parser struct {
+	env    *Env
+	tokens []Token
+	pos    int
+
+	params   []string // Parameters of the function being defined
+	self     string   // Key of the function being defined
+	calls    []string // Keys of called user functions
+	implicit bool     // Implicit multiplication is allowed
+	units    bool     // Names of units are resolved, they are units only after values and conversion operators
+}
+
+func (e *Executor) parse(tokens []Token, params []string, self string) (node, []string, error) {
+	p := &parser{
+		env:      e.env,
+		tokens:   tokens,
+		params:   params,
+		self:     self,
+		implicit: !e.strict,
+	}
+
+	root, err := p.parseExpression(0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if p.pos < len(p.tokens) {
+		return nil, nil, p.unexpected(p.tokens[p.pos])
+	}
+
+	return root, p.calls, nil
+}
+
+func (p *parser) parseExpression(minPrecedence uint) (node, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.pos < len(p.tokens) {
+		token := p.tokens[p.pos]
+		if token.isOpenBracket() {
+			left, err = p.parseIndex(left)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		// Unit after parentheses is multiplied by the value in them like after number, for example: `(1/3) km`
+		if _, call := left.(*callNode); !call && p.tokens[p.pos-1].isCloseParenthesis() && p.isUnit() {
+			unitToken := p.tokens[p.pos]
+			var unit node
+			unit, err = p.parseUnit()
+			if err != nil {
+				return nil, err
+			}
+
+			multiplication, _ := lookupOperator("*", 2)
+			left = &binaryNode{
+				loc:      span(left.location(), unit.location()),
+				opLoc:    unitToken.loc,
+				operator: multiplication,
+				left:     left,
+				right:    unit,
+			}
+			continue
+		}
+		if p.isImplicitMultiplication() {
+			multiplication, _ := lookupOperator("*", 2)
+			if multiplication.precedence <= minPrecedence {
+				break
+			}
+
+			var right node
+			right, err = p.parseExpression(multiplication.precedence)
+			if err != nil {
+				return nil, err
+			}
+
+			loc := span(left.location(), right.location())
+			left = &binaryNode{
+				loc:      loc,
+				opLoc:    loc,
+				operator: multiplication,
+				left:     left,
+				right:    right,
+			}
+			continue
+		}
+		if token.kind != KindOperator || token.isControlFlow() || token.isAssign() {
+			break
+		}
+
+		if operator, ok := p.postfixOperator(token); ok {
+			if operator.precedence <= minPrecedence {
+				break
+			}
+			p.pos++
+
+			left = &unaryNode{
+				loc:      span(left.location(), token.loc),
+				opLoc:    token.loc,
+				operator: operator,
+				operand:  left,
+			}
+			continue
+		}
+
+		operator, ok := lookupOperator(token.text, 2)
+		if !ok {
+			return nil, NewExprError("unknown operator `"+token.text+"`", token.loc)
+		}
+		if operator.precedence <= minPrecedence {
+			break
+		}
+		p.pos++
+
+		if operator.text == opPercentOf.text && !isPercent(left) {
+			return nil, NewExprError("expected percent before `"+token.text+"`", token.loc)
+		}
+
+		if err = p.expectOperand(token); err != nil {
+			return nil, err
+		}
+
+		var right node
+		if (operator.text == "to" || operator.text == "in") && p.isUnit() {
+			right, err = p.parseUnit()
+		} else {
+			right, err = p.parseExpression(operator.precedence)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if percentOp, ok := percentOperator(operator); ok && isPercent(right) {
+			operator = percentOp
+		}
+
+		left = &binaryNode{
+			loc:      span(left.location(), right.location()),
+			opLoc:    token.loc,
+			operator: operator,
+			left:     left,
+			right:    right,
+		}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseOperand() (node, error) {
+	token := p.tokens[p.pos]
+	p.pos++
+
+	switch token.kind {
+	case KindNumber:
+		number, err := parseNumber(token.text)
+		if err != nil {
+			return nil, NewExprError(fmt.Sprintf("invalid number: %s", err), token.loc)
+		}
+		var operand node = &numberNode{
+			loc:   token.loc,
+			value: number,
+		}
+
+		// Unit right after the number is multiplied by it, for example: `5 km`, `4 m^2` or `2i`
+		if p.isUnit() {
+			multiplication, _ := lookupOperator("*", 2)
+			unitToken := p.tokens[p.pos]
+
+			unit, err := p.parseUnit()
+			if err != nil {
+				return nil, err
+			}
+
+			operand = &binaryNode{
+				loc:      span(token.loc, unit.location()),
+				opLoc:    unitToken.loc,
+				operator: multiplication,
+				left:     operand,
+				right:    unit,
+			}
+		}
+
+		return operand, nil
+	case KindIdentifier:
+		if p.pos < len(p.tokens) && p.tokens[p.pos].isOpenParenthesis() {
+			return p.parseCall(token)
+		}
+
+		identifier, err := p.resolveVariable(token)
+		if err != nil {
+			return nil, err
+		}
+		return &identifierNode{
+			loc:        token.loc,
+			identifier: identifier,
+		}, nil
+	case KindOperator:
+		switch {
+		case token.isOpenParenthesis():
+			if p.pos < len(p.tokens) && p.tokens[p.pos].isCloseParenthesis() {
+				return nil, NewExprError("unexpected closing parenthesis", p.tokens[p.pos].loc)
+			}
+			if err := p.expectOperand(token); err != nil {
+				return nil, err
+			}
+
+			expr, err := p.parseExpression(0)
+			if err != nil {
+				return nil, err
+			}
+
+			if p.pos == len(p.tokens) {
+				return nil, NewExprError("unexpected opening parenthesis", token.loc)
+			}
+			if !p.tokens[p.pos].isCloseParenthesis() {
+				return nil, p.unexpected(p.tokens[p.pos])
+			}
+			p.pos++
+
+			return expr, nil
+		case token.isOpenBracket():
+			elements, closeBracket, err := p.parseSequence(token, Token.isCloseBracket)
+			if err != nil {
+				return nil, err
+			}
+
+			return &listNode{
+				loc:      span(token.loc, closeBracket.loc),
+				elements: elements,
+			}, nil
+		case token.isControlFlow() || token.isAssign():
+			return nil, p.unexpected(token)
+		}
+
+		operator, ok := lookupOperator(token.text, 1)
+		if !ok {
+			return nil, NewExprError("unknown operator `"+token.text+"`", token.loc)
+		}
+
+		if err := p.expectOperand(token); err != nil {
+			return nil, err
+		}
+
+		operand, err := p.parseExpression(operator.precedence)
+		if err != nil {
+			return nil, err
+		}
+
+		return &unaryNode{
+			loc:      span(token.loc, operand.location()),
+			opLoc:    token.loc,
+			operator: operator,
+			operand:  operand,
+		}, nil
+	default:
+		return nil, NewExprError(fmt.Sprintf("unknown token kind: %q", token.kind), token.loc)
+	}
+}
+
+func (p *parser) parseCall(name Token) (node, error) {
+	openParenthesis := p.tokens[p.pos]
+	p.pos++
+
+	args, closeParenthesis, err := p.parseSequence(openParenthesis, Token.isCloseParenthesis)
+	if err != nil {
+		return nil, err
+	}
+
+	identifier, err := p.resolveFunction(name, uint(len(args)))
+	if err != nil {
+		return nil, err
+	}
+
+	return &callNode{
+		loc:        span(name.loc, closeParenthesis.loc),
+		nameLoc:    name.loc,
+		identifier: identifier,
+		args:       args,
+	}, nil
+}
+
+// parseSequence parses comma separated expressions until closing token, opening token must be already consumed
+func (p *parser) parseSequence(open Token, isClose func(Token) bool) ([]node, Token, error) {
+	var items []node
+	for {
+		if p.pos == len(p.tokens) {
+			return nil, Token{}, p.unexpected(open)
+		}
+
+		if isClose(p.tokens[p.pos]) {
+			if len(items) != 0 {
+				// Closing token right after comma
+				return nil, Token{}, NewExprError("unexpected "+opComma.name, p.tokens[p.pos-1].loc)
+			}
+			break
+		}
+
+		if err := p.expectOperand(p.tokens[p.pos-1]); err != nil {
+			return nil, Token{}, err
+		}
+
+		item, err := p.parseExpression(0)
+		if err != nil {
+			return nil, Token{}, err
+		}
+		items = append(items, item)
+
+		if p.pos == len(p.tokens) {
+			return nil, Token{}, p.unexpected(open)
+		}
+
+		token := p.tokens[p.pos]
+		if isClose(token) {
+			break
+		}
+		if !token.isComma() {
+			return nil, Token{}, p.unexpected(token)
+		}
+		p.pos++
+	}
+
+	closeToken := p.tokens[p.pos]
+	p.pos++
+
+	return items, closeToken, nil
+}
+
+// parseIndex parses index of the list, for example: `x[0]`
+func (p *parser) parseIndex(list node) (node, error) {
+	openBracket := p.tokens[p.pos]
+	p.pos++
+
+	if err := p.expectOperand(openBracket); err != nil {
+		return nil, err
+	}
+	if p.tokens[p.pos].isCloseBracket() {
+		return nil, NewExprError("expected index", p.tokens[p.pos].loc)
+	}
+
+	index, err := p.parseExpression(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.pos == len(p.tokens) {
+		return nil, p.unexpected(openBracket)
+	}
+	closeBracket := p.tokens[p.pos]
+	if !closeBracket.isCloseBracket() {
+		return nil, p.unexpected(closeBracket)
+	}
+	p.pos++
+
+	return &indexNode{
+		loc:      span(list.location(), closeBracket.loc),
+		indexLoc: span(openBracket.loc, closeBracket.loc),
+		list:     list,
+		index:    index,
+	}, nil
+}
+
+// isImplicitMultiplication reports whether value that ends before the current token is multiplied by the value that
+// starts at it, for example: `2Pi`, `3(4 + 1)`, `(1 + 2)(3 + 4)` or `2√2`
+func (p *parser) isImplicitMultiplication() bool {
+	if !p.implicit || p.pos == 0 || p.pos == len(p.tokens) {
+		return false
+	}
+
+	previous, next := p.tokens[p.pos-1], p.tokens[p.pos]
+	switch {
+	case previous.kind == KindNumber:
+		return next.kind == KindIdentifier || next.isOpenParenthesis() ||
+			next.kind == KindOperator && next.text == squareRootSign
+	case previous.isCloseParenthesis():
+		return next.isOpenParenthesis()
+	default:
+		return false
+	}
+}
+
+// postfixOperator returns postfix operator of the token, operators that are also binary are postfix only if they
+// are not followed by an operand, for example: `5 % 3` is modulo, but `5% + 3` is percent
+func (p *parser) postfixOperator(token Token) (*Operator, bool) {
+	operator, ok := lookupPostfixOperator(token.text)
+	if !ok {
+		return nil, false
+	}
+
+	if _, binary := lookupOperator(token.text, 2); binary && p.pos+1 < len(p.tokens) {
+		next := p.tokens[p.pos+1]
+		if next.kind != KindOperator || next.isOpenParenthesis() || next.isOpenBracket() {
+			return nil, false
+		}
+	}
+	return operator, true
+}
+
+// isPercent reports whether node is a percent, for example: `15%`
+func isPercent(n node) bool {
+	unary, ok := n.(*unaryNode)
+	return ok && unary.operator.postfix && unary.operator.text == opPercent.text
+}
+
+// expectOperand checks that after token there is an operand
+func (p *parser) expectOperand(after Token) error {
+	if p.pos == len(p.tokens) {
+		if after.isOpenParenthesis() || after.isOpenBracket() {
+			return p.unexpected(after)
+		}
+		return NewExprError("expected value after `"+after.text+"`", after.loc)
+	}
+
+	token := p.tokens[p.pos]
+	if token.kind == KindOperator && !token.isOpenParenthesis() && !token.isOpenBracket() && !after.isControlFlow() {
+		return NewExprError("unexpected operator `"+token.text+"`", token.loc)
+	}
+
+	return nil
+}
+
+func (p *parser) unexpected(token Token) error {
+	switch {
+	case token.isOpenParenthesis():
+		return NewExprError("unexpected opening parenthesis", token.loc)
+	case token.isCloseParenthesis():
+		return NewExprError("unexpected closing parenthesis", token.loc)
+	case token.isOpenBracket():
+		return NewExprError("unexpected opening bracket", token.loc)
+	case token.isCloseBracket():
+		return NewExprError("unexpected closing bracket", token.loc)
+	case token.isComma():
+		return NewExprError("unexpected "+opComma.name, token.loc)
+	case token.isAssign():
+		return NewExprError("unexpected "+opAssign.name, token.loc)
+	default:
+		return NewExprError("expected operator, but got `"+token.text+"`", token.loc)
+	}
+}
+
+func (p *parser) resolveVariable(token Token) (*Identifier, error) {
+	identIndex := slices.IndexFunc(knownIdentifiers, func(ident Identifier) bool {
+		return ident.variable && ident.text == token.text
+	})
+	if identIndex >= 0 {
+		return &knownIdentifiers[identIndex], nil
+	}
+
+	if paramIndex := slices.Index(p.params, token.text); paramIndex >= 0 {
+		return paramIdentifier(token.text, paramIndex), nil
+	}
+	if hostIdent, ok := p.env.hostIdentifier(token.text, true, 0); ok {
+		return hostIdent, nil
+	}
+	if _, ok := p.env.Variable(token.text); ok {
+		return variableIdentifier(token.text), nil
+	}
+	if unit, ok := lookupUnit(token.text); ok && p.units {
+		return unitIdentifier(token.text, unit), nil
+	}
+	if token.text == imaginaryIdentifier.text {
+		return &imaginaryIdentifier, nil
+	}
+	if number, ok := strings.CutPrefix(token.text, columnSign); ok {
+		n, err := strconv.Atoi(number)
+		if err != nil || n < 1 {
+			return nil, NewExprError("invalid column `"+token.text+"`, columns are numbered from 1", token.loc)
+		}
+		return columnIdentifier(token.text, n), nil
+	}
+
+	return nil, NewExprError("unknown identifier `"+token.text+"`"+p.suggestion(token.text), token.loc)
+}
+
+// suggestion returns hint with the known name that is the closest to the unknown word, if it is close enough
+func (p *parser) suggestion(word string) string {
+	best, bestDistance := "", len(word)/3+1
+	for _, name := range slices.Concat(knownUniqueIdentifiers, p.env.Names(), p.params) {
+		if name == word {
+			// Name is known, but used in a wrong way, for example: function without arguments
+			return ""
+		}
+		if distance := utils.EditDistance(word, name); distance < bestDistance {
+			best, bestDistance = name, distance
+		}
+	}
+
+	if best == "" {
+		return ""
+	}
+	return ", did you mean `" + best + "`?"
+}
+
+// isUnit reports whether the next token is a unit or the imaginary unit that is not shadowed by other identifiers or
+// used as a function
+func (p *parser) isUnit() bool {
+	if p.pos == len(p.tokens) || p.tokens[p.pos].kind != KindIdentifier ||
+		p.pos+1 < len(p.tokens) && p.tokens[p.pos+1].isOpenParenthesis() {
+		return false
+	}
+
+	units := p.units
+	p.units = true
+	identifier, err := p.resolveVariable(p.tokens[p.pos])
+	p.units = units
+
+	return err == nil && (identifier.name == unitIdentifierName || identifier == &imaginaryIdentifier)
+}
+
+// parseUnit parses unit after a value or conversion operator, for example: `km`, `m^2` or `kg*m/s^2`, names are
+// resolved as units only in it, so misspelled names elsewhere are unknown identifiers
+func (p *parser) parseUnit() (node, error) {
+	units := p.units
+	p.units = true
+	defer func() { p.units = units }()
+
+	multiplication, _ := lookupOperator("*", 2)
+	unit, err := p.parseExpression(multiplication.precedence)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unit is multiplied or divided only by other units, for example: `5 m / s` is speed, but `5 m / 2` is length
+	for p.pos+1 < len(p.tokens) && (p.tokens[p.pos].text == "*" || p.tokens[p.pos].text == "/") {
+		operatorToken := p.tokens[p.pos]
+		p.pos++
+		if !p.isUnit() {
+			p.pos--
+			break
+		}
+
+		operator, _ := lookupOperator(operatorToken.text, 2)
+		right, err := p.parseExpression(multiplication.precedence)
+		if err != nil {
+			return nil, err
+		}
+
+		unit = &binaryNode{
+			loc:      span(unit.location(), right.location()),
+			opLoc:    operatorToken.loc,
+			operator: operator,
+			left:     unit,
+			right:    right,
+		}
+	}
+	return unit, nil
+}
+
+func (p *parser) resolveFunction(token Token, args uint) (*Identifier, error) {
+	identIndex := slices.IndexFunc(knownIdentifiers, func(ident Identifier) bool {
+		return !ident.variable && ident.arity.accepts(args) && ident.text == token.text
+	})
+	if identIndex >= 0 {
+		return &knownIdentifiers[identIndex], nil
+	}
+
+	key := functionKey(token.text, args)
+	if key == p.self {
+		return nil, NewExprError("recursive function `"+p.self+"`", token.loc)
+	}
+
+	if hostIdent, ok := p.env.hostIdentifier(token.text, false, args); ok {
+		return hostIdent, nil
+	}
+	if _, ok := p.env.function(token.text, args); ok {
+		p.calls = append(p.calls, key)
+		return functionIdentifier(token.text, args), nil
+	}
+
+	return nil, p.arityError(token, args)
+}
+
+func (p *parser) arityError(token Token, args uint) error {
+	var arities []arity
+	for _, ident := range slices.Concat(knownIdentifiers, p.env.hostIdentifiers()) {
+		if !ident.variable && ident.text == token.text {
+			arities = append(arities, ident.arity)
+		}
+	}
+	for _, n := range p.env.functionArities(token.text) {
+		arities = append(arities, exactly(n))
+	}
+
+	if len(arities) == 0 {
+		return NewExprError(
+			"unknown function `"+token.text+"/"+strconv.FormatUint(uint64(args), 10)+"`"+p.suggestion(token.text),
+			token.loc,
+		)
+	}
+
+	slices.SortFunc(arities, func(a, b arity) int {
+		return int(a.min) - int(b.min)
+	})
+	expected := make([]string, len(arities))
+	for i, arity := range arities {
+		expected[i] = arity.String()
+	}
+
+	argsText := "arguments"
+	if last := arities[len(arities)-1]; last.max == 1 || last.max == unbounded && last.min == 1 {
+		argsText = "argument"
+	}
+
+	return NewExprError(fmt.Sprintf(
+		"function `%s` expects %s %s, but got %d", token.text, strings.Join(expected, " or "), argsText, args,
+	), token.loc)
+}
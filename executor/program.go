@@ -0,0 +1,103 @@
+package executor
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// Program is a compiled statement that can be run many times without parsing it again, program is immutable and
+// safe for concurrent use
+//
+// Built-in and host defined identifiers are bound at compile time, while user variables and functions are resolved
+// in the environment program runs in, modes and format of the executor are copied at compile time, so later changes
+// of the executor don't affect compiled programs
+type Program struct {
+	env      *Env      // Environment of the executor, used if program runs without environment
+	target   *Token    // Assignment target, nil for expressions and function definitions
+	function *Function // Function definition, nil for expressions and assignments
+	root     node
+	intMode  IntMode
+	exact    bool
+	complex  bool
+	angle    AngleMode
+	format   Format
+}
+
+// Compile compiles expression, assignment or function definition into the program, identifiers are checked against
+// executor's environment
+func (e *Executor) Compile(expression string) (*Program, error) {
+	program, err := e.compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	if program == nil {
+		return nil, fmt.Errorf("empty expression")
+	}
+	return program, nil
+}
+
+// Run evaluates program in the environment, if env is nil, environment of the executor is used, assigned values and
+// defined functions are stored in the environment, for function definitions zero is returned
+func (p *Program) Run(env *Env) (Value, error) {
+	return p.run(env, true, defaultPrecision)
+}
+
+// RunWithPrecision evaluates program like Run, but intermediate results are computed with enough digits for the
+// precision digits after the point of the result and for the format of the executor at compile time
+func (p *Program) RunWithPrecision(env *Env, precision int32) (Value, error) {
+	if precision > MaxPrecision {
+		return nil, fmt.Errorf("precision must not be greater than %d", MaxPrecision)
+	}
+	return p.runWithPrecision(env, true, precision)
+}
+
+// runWithPrecision evaluates program with the working precision of the precision digits after the point
+func (p *Program) runWithPrecision(env *Env, commit bool, precision int32) (Value, error) {
+	result, err := p.run(env, commit, workingPrecision(precision))
+	if err != nil || p.function != nil {
+		return result, err
+	}
+
+	// Small numbers don't have enough significant digits for the format, so they are evaluated again with more digits
+	if required := min(p.format.requiredPrecision(result, precision), MaxPrecision); required > precision {
+		return p.run(env, commit, workingPrecision(required))
+	}
+	return result, nil
+}
+
+// run evaluates program with intermediate results rounded to the working precision
+func (p *Program) run(env *Env, commit bool, precision int32) (Value, error) {
+	if env == nil {
+		env = p.env
+	}
+
+	if p.function != nil {
+		if env.calls(p.function.key(), p.function.calls, make(map[string]bool)) {
+			return nil, NewExprError("recursive function `"+p.function.key()+"`", p.target.loc)
+		}
+
+		if commit {
+			if err := env.setFunction(p.function); err != nil {
+				return nil, NewExprError(err.Error(), p.target.loc)
+			}
+		}
+
+		return NewNumber(decimal.Zero), nil
+	}
+
+	result, err := evaluate(p.root, &scope{
+		env: env, intMode: p.intMode, exact: p.exact, complex: p.complex, angle: p.angle, precision: precision,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if p.target != nil && commit {
+		if err = env.SetVariable(p.target.text, result); err != nil {
+			return nil, NewExprError(err.Error(), p.target.loc)
+		}
+	}
+
+	return result, nil
+}
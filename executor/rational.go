@@ -0,0 +1,197 @@
+package executor
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/shopspring/decimal"
+)
+
+// ExactMode is a mode in which numbers are exact fractions as long as only rational operations are used, other
+// operations fall back to decimals
+type ExactMode int
+
+const (
+	ExactOff      ExactMode = iota // Numbers are decimals
+	ExactFraction                  // Numbers are fractions printed as `4/3`
+	ExactMixed                     // Numbers are fractions printed as mixed numbers `1 1/3`
+)
+
+var exactModeNames = map[ExactMode]string{
+	ExactOff:      "off",
+	ExactFraction: "on",
+	ExactMixed:    "mixed",
+}
+
+// ParseExactMode parses exact mode, one of `off`, `on` or `mixed`, empty string disables it
+func ParseExactMode(text string) (ExactMode, error) {
+	if text == "" {
+		return ExactOff, nil
+	}
+
+	for mode, name := range exactModeNames {
+		if name == text {
+			return mode, nil
+		}
+	}
+	return ExactOff, fmt.Errorf("invalid exact mode `%s`, expected `off`, `on` or `mixed`", text)
+}
+
+// Enabled reports whether exact mode is enabled
+func (m ExactMode) Enabled() bool {
+	return m != ExactOff
+}
+
+func (m ExactMode) String() string {
+	return exactModeNames[m]
+}
+
+func (m ExactMode) validate() error {
+	if _, ok := exactModeNames[m]; !ok {
+		return fmt.Errorf("unknown exact mode %d", m)
+	}
+	return nil
+}
+
+// maxExactExponent is the maximal exponent rationals are raised to exactly, larger exponents fall back to decimals
+const maxExactExponent = 1 << 12
+
+// Rational is an exact fraction of two integers
+type Rational struct {
+	value *big.Rat
+}
+
+// NewRational creates new rational from its numerator and denominator, denominator must not be zero
+func NewRational(numerator, denominator *big.Int) Rational {
+	return Rational{value: new(big.Rat).SetFrac(numerator, denominator)}
+}
+
+// Rat returns copy of the rational value
+func (r Rational) Rat() *big.Rat {
+	return new(big.Rat).Set(r.value)
+}
+
+// Decimal returns value of the rational rounded to the default precision
+func (r Rational) Decimal() decimal.Decimal {
+	return r.decimal(defaultPrecision)
+}
+
+func (r Rational) decimal(precision int32) decimal.Decimal {
+	return decimal.NewFromBigRat(r.value, precision)
+}
+
+func (r Rational) String() string {
+	return r.value.RatString()
+}
+
+func (r Rational) isValue() {}
+
+func newRational(value decimal.Decimal) Rational {
+	return Rational{value: value.Rat()}
+}
+
+// rationals returns both values as rationals if both of them are rationals
+func rationals(v1, v2 Value) (*big.Rat, *big.Rat, bool) {
+	r1, ok1 := v1.(Rational)
+	r2, ok2 := v2.(Rational)
+	if !ok1 || !ok2 {
+		return nil, nil, false
+	}
+	return r1.value, r2.value, true
+}
+
+// powRational raises rational to the integer power, ok is false if exponent is not an integer or too large
+func powRational(base, exponent *big.Rat) (result *big.Rat, ok bool, err error) {
+	if !exponent.IsInt() || exponent.Num().CmpAbs(big.NewInt(maxExactExponent)) > 0 {
+		return nil, false, nil
+	}
+
+	n := exponent.Num().Int64()
+	switch {
+	case base.Sign() == 0 && n == 0:
+		return nil, true, fmt.Errorf("undefined value (0 ^ 0)")
+	case base.Sign() == 0 && n < 0:
+		return nil, true, fmt.Errorf("infinity")
+	}
+
+	absN := big.NewInt(n)
+	absN.Abs(absN)
+	numerator := new(big.Int).Exp(base.Num(), absN, nil)
+	denominator := new(big.Int).Exp(base.Denom(), absN, nil)
+	if n < 0 {
+		numerator, denominator = denominator, numerator
+	}
+
+	return new(big.Rat).SetFrac(numerator, denominator), true, nil
+}
+
+func floorRational(r *big.Rat) *big.Rat {
+	quotient := new(big.Int).Div(r.Num(), r.Denom()) // Euclidean division rounds toward negative infinity
+	return new(big.Rat).SetInt(quotient)
+}
+
+func ceilRational(r *big.Rat) *big.Rat {
+	return new(big.Rat).Neg(floorRational(new(big.Rat).Neg(r)))
+}
+
+// roundRational rounds half away from zero the same way as decimals are rounded
+func roundRational(r *big.Rat) *big.Rat {
+	half := big.NewRat(1, 2)
+	if r.Sign() < 0 {
+		return ceilRational(new(big.Rat).Sub(r, half))
+	}
+	return floorRational(new(big.Rat).Add(r, half))
+}
+
+func absRational(r *big.Rat) *big.Rat {
+	return new(big.Rat).Abs(r)
+}
+
+func roundDecimal(d decimal.Decimal) decimal.Decimal {
+	return d.Round(0)
+}
+
+// applyExactOp applies function to the magnitude of the value keeping its unit, rationals are processed exactly
+func applyExactOp(
+	exact func(v1 *big.Rat) *big.Rat, apply func(v1 decimal.Decimal) decimal.Decimal,
+) func(s *scope, args []Value) (Value, error) {
+	return func(s *scope, args []Value) (Value, error) {
+		switch v := args[0].(type) {
+		case Rational:
+			return Rational{value: exact(v.value)}, nil
+		case Quantity:
+			return newQuantity(apply(v.value), v.unit, s.precision), nil
+		default:
+			value, err := toDecimal(v, s.precision)
+			if err != nil {
+				return nil, err
+			}
+			return NewNumber(apply(value)), nil
+		}
+	}
+}
+
+// formatRational formats rational as a fraction or mixed number in the base, only digits grouping of the format is
+// used, since numerator and denominator are integers
+func formatRational(value *big.Rat, base int, mixed bool, format Format) string {
+	integers := Format{GroupSeparator: format.GroupSeparator}
+	formatInteger := func(value *big.Int) string {
+		return formatNumber(decimal.NewFromBigInt(value, 0), 0, base, integers)
+	}
+
+	if value.IsInt() {
+		return formatInteger(value.Num())
+	}
+
+	numerator := value.Num()
+	if !mixed || numerator.CmpAbs(value.Denom()) < 0 {
+		return formatInteger(numerator) + "/" + formatInteger(value.Denom())
+	}
+
+	integer, remainder := new(big.Int).QuoRem(numerator, value.Denom(), new(big.Int))
+	return formatInteger(integer) + " " + formatInteger(remainder.Abs(remainder)) + "/" + formatInteger(value.Denom())
+}
+
+func copyRational(r *big.Rat) *big.Rat {
+	return new(big.Rat).Set(r)
+}
@@ -1,24 +1,20 @@
 package executor
 
 import (
+	"encoding/json"
 	"fmt"
-
-	"github.com/shopspring/decimal"
 )
 
 type Token struct {
 	text string
 	loc  Location
 	kind TokenKind
-
-	number     *decimal.Decimal
-	operator   *Operator
-	identifier *Identifier
 }
 
 func (t Token) isControlFlow() bool {
 	return t.kind == KindOperator &&
-		(t.text == opOpenParenthesis.text || t.text == opCloseParenthesis.text || t.text == opComma.text)
+		(t.text == opOpenParenthesis.text || t.text == opCloseParenthesis.text || t.text == opComma.text ||
+			t.text == opOpenBracket.text || t.text == opCloseBracket.text)
 }
 
 func (t Token) isOpenParenthesis() bool {
@@ -29,40 +25,70 @@ func (t Token) isCloseParenthesis() bool {
 	return t.kind == KindOperator && t.text == opCloseParenthesis.text
 }
 
+func (t Token) isOpenBracket() bool {
+	return t.kind == KindOperator && t.text == opOpenBracket.text
+}
+
+func (t Token) isCloseBracket() bool {
+	return t.kind == KindOperator && t.text == opCloseBracket.text
+}
+
 func (t Token) isComma() bool {
 	return t.kind == KindOperator && t.text == opComma.text
 }
 
+func (t Token) isAssign() bool {
+	return t.kind == KindOperator && t.text == opAssign.text
+}
+
+func (t Token) isSeparator() bool {
+	return t.kind == KindSeparator
+}
+
 func (t Token) String() string {
-	s := fmt.Sprintf("{%s}:[%d-%d] `%s`", t.kind, t.loc.Start, t.loc.End, t.text)
-	if t.number != nil {
-		s += fmt.Sprintf(" %s", t.number.String())
-	}
-	if t.operator != nil {
-		s += fmt.Sprintf(" %s", t.operator.name)
-	}
-	if t.identifier != nil {
-		s += fmt.Sprintf(" %s", t.identifier.name)
-		if !t.identifier.variable {
-			s += fmt.Sprintf("/%d", t.identifier.arity)
-		}
-	}
-	return s
+	return fmt.Sprintf("{%s}:[%d-%d] `%s`", t.kind, t.loc.Start, t.loc.End, t.text)
+}
+
+// MarshalJSON encodes token with its kind and location, offsets are in bytes
+func (t Token) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Text  string    `json:"text"`
+		Kind  TokenKind `json:"kind"`
+		Start int       `json:"start"`
+		End   int       `json:"end"`
+	}{
+		Text:  t.text,
+		Kind:  t.kind,
+		Start: t.loc.Start,
+		End:   t.loc.End,
+	})
 }
 
 type TokenKind string
 
 const (
-	KindNumber     TokenKind = "number"     // `123`, `1.12`, `12`, `1_2_3`
-	KindOperator   TokenKind = "operator"   // `+`, `-`, `//`, `(`
+	KindNumber     TokenKind = "number"     // `123`, `1.12`, `12`, `1_2_3`, `0xFF`, `0b1010`, `0o755`
+	KindOperator   TokenKind = "operator"   // `+`, `-`, `//`, `(`, `[`
 	KindIdentifier TokenKind = "identifier" // `abc`, `a12`, `a_b_1`
+	KindSeparator  TokenKind = "separator"  // `;`, new line
 )
 
+// Location is a part of expression, offsets are in bytes and columns are positions on the screen, characters like
+// `π` take one column but more than one byte and wide characters take two columns
 type Location struct {
-	Start int
-	End   int
+	Start       int // Offset of the first byte
+	End         int // Offset after the last byte
+	Line        int // Line of the first character, lines and columns start from zero
+	StartColumn int // Column of the first character in its line
+	EndColumn   int // Column after the last character in its line
 }
 
+// Size returns number of bytes
 func (l Location) Size() int {
 	return l.End - l.Start
 }
+
+// Width returns number of columns
+func (l Location) Width() int {
+	return l.EndColumn - l.StartColumn
+}
@@ -0,0 +1,297 @@
+package executor
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// Dimension is a vector of exponents of SI base dimensions and angle
+type Dimension [8]int
+
+var dimensionNames = [len(Dimension{})]string{"length", "mass", "time", "current", "temperature", "amount",
+	"luminosity", "angle"}
+
+func (d Dimension) add(other Dimension, sign int) Dimension {
+	for i := range d {
+		d[i] += sign * other[i]
+	}
+	return d
+}
+
+func (d Dimension) scale(n int) Dimension {
+	for i := range d {
+		d[i] *= n
+	}
+	return d
+}
+
+func (d Dimension) dimensionless() bool {
+	return d == Dimension{}
+}
+
+func (d Dimension) String() string {
+	if d.dimensionless() {
+		return "dimensionless"
+	}
+
+	var parts []string
+	for i, exp := range d {
+		switch exp {
+		case 0:
+		case 1:
+			parts = append(parts, dimensionNames[i])
+		default:
+			parts = append(parts, dimensionNames[i]+"^"+strconv.Itoa(exp))
+		}
+	}
+	return strings.Join(parts, "*")
+}
+
+// unitDef is a definition of named unit
+type unitDef struct {
+	symbol     string
+	name       string
+	factor     decimal.Decimal // Value of the unit in SI base units
+	dim        Dimension
+	prefixable bool
+}
+
+type unitPrefix struct {
+	symbol string
+	factor decimal.Decimal
+}
+
+func dim(exps ...int) Dimension {
+	d := Dimension{}
+	copy(d[:], exps)
+	return d
+}
+
+// Dimensions of commonly used quantities
+var (
+	dimVelocity     = dim(1, 0, -1)
+	dimForce        = dim(1, 1, -2)
+	dimEnergy       = dim(2, 1, -2)
+	dimPower        = dim(2, 1, -3)
+	dimPressure     = dim(-1, 1, -2)
+	dimCharge       = dim(0, 0, 1, 1)
+	dimVoltage      = dim(2, 1, -3, -1)
+	dimCapacitance  = dim(-2, -1, 4, 2)
+	dimResistance   = dim(2, 1, -3, -2)
+	dimConductance  = dim(-2, -1, 3, 2)
+	dimMagneticFlux = dim(2, 1, -2, -1)
+	dimFluxDensity  = dim(0, 1, -2, -1)
+	dimInductance   = dim(2, 1, -2, -2)
+	dimVolume       = dim(3)
+	dimAngle        = dim(0, 0, 0, 0, 0, 0, 0, 1)
+)
+
+func mustDecimal(value string) decimal.Decimal {
+	return decimal.RequireFromString(value)
+}
+
+// piFraction returns Pi divided by n with all known digits of Pi
+func piFraction(n int64) decimal.Decimal {
+	return constPi.DivRound(decimal.NewFromInt(n), -constPi.Exponent())
+}
+
+var knownUnits = []unitDef{
+	// SI base units, gram is used instead of kilogram to allow prefixes
+	{symbol: "m", name: "meter", factor: mustDecimal("1"), dim: dim(1), prefixable: true},
+	{symbol: "g", name: "gram", factor: mustDecimal("0.001"), dim: dim(0, 1), prefixable: true},
+	{symbol: "s", name: "second", factor: mustDecimal("1"), dim: dim(0, 0, 1), prefixable: true},
+	{symbol: "A", name: "ampere", factor: mustDecimal("1"), dim: dim(0, 0, 0, 1), prefixable: true},
+	{symbol: "K", name: "kelvin", factor: mustDecimal("1"), dim: dim(0, 0, 0, 0, 1), prefixable: true},
+	{symbol: "mol", name: "mole", factor: mustDecimal("1"), dim: dim(0, 0, 0, 0, 0, 1), prefixable: true},
+	{symbol: "cd", name: "candela", factor: mustDecimal("1"), dim: dim(0, 0, 0, 0, 0, 0, 1), prefixable: true},
+
+	// SI derived units
+	{symbol: "Hz", name: "hertz", factor: mustDecimal("1"), dim: dim(0, 0, -1), prefixable: true},
+	{symbol: "N", name: "newton", factor: mustDecimal("1"), dim: dimForce, prefixable: true},
+	{symbol: "Pa", name: "pascal", factor: mustDecimal("1"), dim: dimPressure, prefixable: true},
+	{symbol: "J", name: "joule", factor: mustDecimal("1"), dim: dimEnergy, prefixable: true},
+	{symbol: "W", name: "watt", factor: mustDecimal("1"), dim: dimPower, prefixable: true},
+	{symbol: "C", name: "coulomb", factor: mustDecimal("1"), dim: dimCharge, prefixable: true},
+	{symbol: "V", name: "volt", factor: mustDecimal("1"), dim: dimVoltage, prefixable: true},
+	{symbol: "F", name: "farad", factor: mustDecimal("1"), dim: dimCapacitance, prefixable: true},
+	{symbol: "ohm", name: "ohm", factor: mustDecimal("1"), dim: dimResistance, prefixable: true},
+	{symbol: "S", name: "siemens", factor: mustDecimal("1"), dim: dimConductance, prefixable: true},
+	{symbol: "Wb", name: "weber", factor: mustDecimal("1"), dim: dimMagneticFlux, prefixable: true},
+	{symbol: "T", name: "tesla", factor: mustDecimal("1"), dim: dimFluxDensity, prefixable: true},
+	{symbol: "H", name: "henry", factor: mustDecimal("1"), dim: dimInductance, prefixable: true},
+
+	// Angles are not dimensionless to not be converted into numbers
+	{symbol: "rad", name: "radian", factor: mustDecimal("1"), dim: dimAngle, prefixable: true},
+	{symbol: "deg", name: "degree", factor: piFraction(180), dim: dimAngle},
+	{symbol: degreeSign, name: "degree", factor: piFraction(180), dim: dimAngle},
+	{symbol: "grad", name: "gradian", factor: piFraction(200), dim: dimAngle},
+
+	// Non-SI units accepted for use with SI
+	{symbol: "min", name: "minute", factor: mustDecimal("60"), dim: dim(0, 0, 1)},
+	{symbol: "h", name: "hour", factor: mustDecimal("3600"), dim: dim(0, 0, 1)},
+	{symbol: "day", name: "day", factor: mustDecimal("86400"), dim: dim(0, 0, 1)},
+	{symbol: "L", name: "liter", factor: mustDecimal("0.001"), dim: dimVolume, prefixable: true},
+	{symbol: "t", name: "tonne", factor: mustDecimal("1000"), dim: dim(0, 1)},
+	{symbol: "bar", name: "bar", factor: mustDecimal("100000"), dim: dimPressure, prefixable: true},
+	{symbol: "atm", name: "atmosphere", factor: mustDecimal("101325"), dim: dimPressure},
+	{symbol: "eV", name: "electronvolt", factor: mustDecimal("1.602176634e-19"), dim: dimEnergy, prefixable: true},
+	{symbol: "Wh", name: "watt-hour", factor: mustDecimal("3600"), dim: dimEnergy, prefixable: true},
+	{symbol: "cal", name: "calorie", factor: mustDecimal("4.184"), dim: dimEnergy, prefixable: true},
+
+	// Imperial and US customary units
+	{symbol: "inch", name: "inch", factor: mustDecimal("0.0254"), dim: dim(1)},
+	{symbol: "ft", name: "foot", factor: mustDecimal("0.3048"), dim: dim(1)},
+	{symbol: "yd", name: "yard", factor: mustDecimal("0.9144"), dim: dim(1)},
+	{symbol: "mi", name: "mile", factor: mustDecimal("1609.344"), dim: dim(1)},
+	{symbol: "nmi", name: "nautical mile", factor: mustDecimal("1852"), dim: dim(1)},
+	{symbol: "lb", name: "pound", factor: mustDecimal("0.45359237"), dim: dim(0, 1)},
+	{symbol: "oz", name: "ounce", factor: mustDecimal("0.028349523125"), dim: dim(0, 1)},
+	{symbol: "mph", name: "mile per hour", factor: mustDecimal("0.44704"), dim: dimVelocity},
+	{symbol: "kn", name: "knot", factor: mustDecimal("0.514444444444444444444444444444"), dim: dimVelocity},
+	{symbol: "gal", name: "US gallon", factor: mustDecimal("0.003785411784"), dim: dimVolume},
+	{symbol: "psi", name: "pound per square inch", factor: mustDecimal("6894.757293168361"), dim: dimPressure},
+	{symbol: "hp", name: "horsepower", factor: mustDecimal("745.69987158227022"), dim: dimPower},
+	{symbol: "BTU", name: "British thermal unit", factor: mustDecimal("1055.05585262"), dim: dimEnergy},
+}
+
+var knownUnitPrefixes = []unitPrefix{
+	{symbol: "da", factor: mustDecimal("1e1")},
+	{symbol: "Y", factor: mustDecimal("1e24")},
+	{symbol: "Z", factor: mustDecimal("1e21")},
+	{symbol: "E", factor: mustDecimal("1e18")},
+	{symbol: "P", factor: mustDecimal("1e15")},
+	{symbol: "T", factor: mustDecimal("1e12")},
+	{symbol: "G", factor: mustDecimal("1e9")},
+	{symbol: "M", factor: mustDecimal("1e6")},
+	{symbol: "k", factor: mustDecimal("1e3")},
+	{symbol: "h", factor: mustDecimal("1e2")},
+	{symbol: "d", factor: mustDecimal("1e-1")},
+	{symbol: "c", factor: mustDecimal("1e-2")},
+	{symbol: "m", factor: mustDecimal("1e-3")},
+	{symbol: "u", factor: mustDecimal("1e-6")},
+	{symbol: "n", factor: mustDecimal("1e-9")},
+	{symbol: "p", factor: mustDecimal("1e-12")},
+	{symbol: "f", factor: mustDecimal("1e-15")},
+	{symbol: "a", factor: mustDecimal("1e-18")},
+	{symbol: "z", factor: mustDecimal("1e-21")},
+	{symbol: "y", factor: mustDecimal("1e-24")},
+}
+
+// lookupUnit returns unit by its symbol, symbol may have SI prefix
+func lookupUnit(symbol string) (Unit, bool) {
+	for _, def := range knownUnits {
+		if def.symbol == symbol {
+			return Unit{{symbol: symbol, factor: def.factor, dim: def.dim, exp: 1}}, true
+		}
+	}
+
+	for _, prefix := range knownUnitPrefixes {
+		if !strings.HasPrefix(symbol, prefix.symbol) {
+			continue
+		}
+
+		for _, def := range knownUnits {
+			if def.prefixable && def.symbol == symbol[len(prefix.symbol):] {
+				return Unit{{symbol: symbol, factor: prefix.factor.Mul(def.factor), dim: def.dim, exp: 1}}, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// unitPart is a named unit raised to the power
+type unitPart struct {
+	symbol string
+	factor decimal.Decimal
+	dim    Dimension
+	exp    int
+}
+
+// Unit is a product of named units raised to the powers, for example: `kg*m/s^2`
+type Unit []unitPart
+
+func (u Unit) mul(other Unit, sign int) Unit {
+	result := slices.Clone(u)
+	for _, part := range other {
+		i := slices.IndexFunc(result, func(p unitPart) bool { return p.symbol == part.symbol })
+		if i < 0 {
+			part.exp *= sign
+			result = append(result, part)
+			continue
+		}
+		result[i].exp += sign * part.exp
+	}
+
+	return slices.DeleteFunc(result, func(p unitPart) bool { return p.exp == 0 })
+}
+
+func (u Unit) pow(n int) Unit {
+	result := slices.Clone(u)
+	for i := range result {
+		result[i].exp *= n
+	}
+	return slices.DeleteFunc(result, func(p unitPart) bool { return p.exp == 0 })
+}
+
+// factor returns value of the unit in SI base units as a fraction to avoid rounding in conversions
+func (u Unit) factor() (numerator, denominator decimal.Decimal) {
+	numerator, denominator = decimal.NewFromInt(1), decimal.NewFromInt(1)
+	for _, part := range u {
+		if part.exp > 0 {
+			numerator = numerator.Mul(part.factor.Pow(decimal.NewFromInt(int64(part.exp))))
+		} else {
+			denominator = denominator.Mul(part.factor.Pow(decimal.NewFromInt(int64(-part.exp))))
+		}
+	}
+	return numerator, denominator
+}
+
+func (u Unit) dimension() Dimension {
+	d := Dimension{}
+	for _, part := range u {
+		d = d.add(part.dim.scale(part.exp), 1)
+	}
+	return d
+}
+
+func (u Unit) String() string {
+	var numerator, denominator []string
+	for _, part := range u {
+		exp := part.exp
+		if exp < 0 {
+			exp = -exp
+		}
+
+		s := part.symbol
+		if exp != 1 {
+			s += "^" + strconv.Itoa(exp)
+		}
+
+		if part.exp > 0 {
+			numerator = append(numerator, s)
+		} else {
+			denominator = append(denominator, s)
+		}
+	}
+
+	if len(denominator) == 0 {
+		return strings.Join(numerator, "*")
+	}
+	if len(numerator) == 0 {
+		// Units without numerator are written with negative exponents, for example: `s^-1`
+		for i, part := range u {
+			denominator[i] = part.symbol + "^" + strconv.Itoa(part.exp)
+		}
+		return strings.Join(denominator, "*")
+	}
+
+	s := strings.Join(numerator, "*") + "/"
+	if len(denominator) == 1 {
+		return s + denominator[0]
+	}
+	return s + "(" + strings.Join(denominator, "*") + ")"
+}
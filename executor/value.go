@@ -0,0 +1,372 @@
+package executor
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/shopspring/decimal"
+)
+
+// Value is a result of expression evaluation, it is either [Number], [Rational], [Quantity], [Complex] or [List]
+type Value interface {
+	String() string
+	isValue()
+}
+
+// Number is a dimensionless number
+type Number struct {
+	value decimal.Decimal
+}
+
+// NewNumber creates new number
+func NewNumber(value decimal.Decimal) Number {
+	return Number{value: value}
+}
+
+// Decimal returns value of the number
+func (n Number) Decimal() decimal.Decimal {
+	return n.value
+}
+
+func (n Number) String() string {
+	return n.value.String()
+}
+
+func (n Number) isValue() {}
+
+// Quantity is a number with a unit, for example: `5 km`, quantities without dimension are converted into numbers
+type Quantity struct {
+	value decimal.Decimal // Value in the unit
+	unit  Unit
+}
+
+// Magnitude returns value of the quantity in its unit
+func (q Quantity) Magnitude() decimal.Decimal {
+	return q.value
+}
+
+// Unit returns unit of the quantity
+func (q Quantity) Unit() string {
+	return q.unit.String()
+}
+
+func (q Quantity) String() string {
+	return formatQuantity(q.value.String(), q.unit)
+}
+
+// formatQuantity joins formatted value and unit, degree sign is written right after the value
+func formatQuantity(value string, unit Unit) string {
+	if unit.String() == degreeSign {
+		return value + degreeSign
+	}
+	return value + " " + unit.String()
+}
+
+func (q Quantity) isValue() {}
+
+// newQuantity creates quantity, if unit is dimensionless number is returned instead
+func newQuantity(value decimal.Decimal, unit Unit, precision int32) Value {
+	if unit.dimension().dimensionless() {
+		if len(unit) != 0 {
+			numerator, denominator := unit.factor()
+			value = value.Mul(numerator).DivRound(denominator, precision)
+		}
+		return Number{value: value}
+	}
+	return Quantity{value: value, unit: unit}
+}
+
+// magnitude returns value and unit of the value, numbers have no unit, rationals are rounded to the precision
+func magnitude(v Value, precision int32) (decimal.Decimal, Unit) {
+	switch v := v.(type) {
+	case Number:
+		return v.value, nil
+	case Rational:
+		return v.decimal(precision), nil
+	case Quantity:
+		return v.value, v.unit
+	default:
+		panic(fmt.Sprintf("unknown value: %T", v))
+	}
+}
+
+// toDecimal returns value of the number or rational rounded to the precision, quantities and complex numbers can't be
+// converted
+func toDecimal(v Value, precision int32) (decimal.Decimal, error) {
+	switch v := v.(type) {
+	case Number:
+		return v.value, nil
+	case Rational:
+		return v.decimal(precision), nil
+	case Complex:
+		return decimal.Zero, fmt.Errorf("expected real value, but got `%s`", v)
+	case List:
+		return decimal.Zero, fmt.Errorf("expected number, but got list `%s`", v)
+	default:
+		return decimal.Zero, fmt.Errorf("expected dimensionless value, but got `%s`", v)
+	}
+}
+
+// convert converts value into the unit, dimensions of the value and the unit must match
+func convert(v Value, unit Unit, precision int32) (decimal.Decimal, error) {
+	value, from := magnitude(v, precision)
+	if from.dimension() != unit.dimension() {
+		return decimal.Zero, fmt.Errorf("can't convert %s to %s", from.dimension(), unit.dimension())
+	}
+	if from.String() == unit.String() {
+		return value, nil
+	}
+
+	fromNumerator, fromDenominator := from.factor()
+	toNumerator, toDenominator := unit.factor()
+	return value.Mul(fromNumerator).Mul(toDenominator).
+		DivRound(fromDenominator.Mul(toNumerator), precision), nil
+}
+
+// sameDimension converts the second value into the unit of the first one
+func sameDimension(v1, v2 Value, precision int32) (decimal.Decimal, decimal.Decimal, Unit, error) {
+	value, unit := magnitude(v1, precision)
+	if _, other := magnitude(v2, precision); other.dimension() != unit.dimension() {
+		return decimal.Zero, decimal.Zero, nil, fmt.Errorf(
+			"incompatible dimensions %s and %s", unit.dimension(), other.dimension(),
+		)
+	}
+
+	other, err := convert(v2, unit, precision)
+	return value, other, unit, err
+}
+
+func add(v1, v2 Value, precision int32) (Value, error) {
+	if isComplex(v1, v2) {
+		return applyComplexOp(v1, v2, precision, Complex.add)
+	}
+	if r1, r2, ok := rationals(v1, v2); ok {
+		return Rational{value: new(big.Rat).Add(r1, r2)}, nil
+	}
+
+	value, other, unit, err := sameDimension(v1, v2, precision)
+	if err != nil {
+		return nil, err
+	}
+	return newQuantity(value.Add(other), unit, precision), nil
+}
+
+func sub(v1, v2 Value, precision int32) (Value, error) {
+	if isComplex(v1, v2) {
+		return applyComplexOp(v1, v2, precision, Complex.sub)
+	}
+	if r1, r2, ok := rationals(v1, v2); ok {
+		return Rational{value: new(big.Rat).Sub(r1, r2)}, nil
+	}
+
+	value, other, unit, err := sameDimension(v1, v2, precision)
+	if err != nil {
+		return nil, err
+	}
+	return newQuantity(value.Sub(other), unit, precision), nil
+}
+
+func mul(v1, v2 Value, precision int32) (Value, error) {
+	if isComplex(v1, v2) {
+		return applyComplexOp(v1, v2, precision, Complex.mul)
+	}
+	if r1, r2, ok := rationals(v1, v2); ok {
+		return Rational{value: new(big.Rat).Mul(r1, r2)}, nil
+	}
+
+	value1, unit1 := magnitude(v1, precision)
+	value2, unit2 := magnitude(v2, precision)
+	return newQuantity(value1.Mul(value2), unit1.mul(unit2, 1), precision), nil
+}
+
+func div(v1, v2 Value, precision int32) (Value, error) {
+	if isComplex(v1, v2) {
+		return applyComplexOp(v1, v2, precision, func(c1, c2 Complex) (Value, error) {
+			return c1.div(c2, precision)
+		})
+	}
+
+	value1, unit1 := magnitude(v1, precision)
+	value2, unit2 := magnitude(v2, precision)
+	if value2.IsZero() {
+		return nil, fmt.Errorf("division by zero")
+	}
+
+	if r1, r2, ok := rationals(v1, v2); ok {
+		return Rational{value: new(big.Rat).Quo(r1, r2)}, nil
+	}
+	return newQuantity(value1.DivRound(value2, precision), unit1.mul(unit2, -1), precision), nil
+}
+
+func pow(v1, v2 Value, precision int32) (Value, error) {
+	if isComplex(v1, v2) {
+		return applyComplexOp(v1, v2, precision, func(c1, c2 Complex) (Value, error) {
+			return c1.pow(c2, precision)
+		})
+	}
+	if r1, r2, ok := rationals(v1, v2); ok {
+		result, exact, err := powRational(r1, r2)
+		if err != nil {
+			return nil, err
+		}
+		if exact {
+			return Rational{value: result}, nil
+		}
+	}
+
+	exponent, err := toDecimal(v2, precision)
+	if err != nil {
+		return nil, fmt.Errorf("exponent must be dimensionless")
+	}
+
+	value, unit := magnitude(v1, precision)
+	if len(unit) != 0 && !exponent.IsInteger() {
+		return nil, fmt.Errorf("exponent of quantity must be an integer")
+	}
+
+	result, err := power(value, exponent, precision)
+	if err != nil {
+		return nil, err
+	}
+	return newQuantity(result, unit.pow(int(exponent.IntPart())), precision), nil
+}
+
+func neg(v Value) Value {
+	switch v := v.(type) {
+	case Rational:
+		return Rational{value: new(big.Rat).Neg(v.value)}
+	case Complex:
+		return Complex{re: v.re.Neg(), im: v.im.Neg()}
+	}
+
+	switch v := v.(type) {
+	case Number:
+		return Number{value: v.value.Neg()}
+	case Quantity:
+		return Quantity{value: v.value.Neg(), unit: v.unit}
+	default:
+		panic(fmt.Sprintf("unknown value: %T", v))
+	}
+}
+
+// compare compares values with the same dimension, complex numbers and lists can't be compared
+func compare(v1, v2 Value, precision int32) (int, error) {
+	if isComplex(v1, v2) {
+		return 0, fmt.Errorf("complex numbers can't be compared")
+	}
+	if isList(v1, v2) {
+		return 0, fmt.Errorf("lists can't be compared")
+	}
+	if r1, r2, ok := rationals(v1, v2); ok {
+		return r1.Cmp(r2), nil
+	}
+
+	value, other, _, err := sameDimension(v1, v2, precision)
+	if err != nil {
+		return 0, err
+	}
+	return value.Cmp(other), nil
+}
+
+// equal reports whether values are equal, unlike compare it supports complex numbers and lists
+func equal(v1, v2 Value, precision int32) (bool, error) {
+	if isList(v1, v2) {
+		l1, ok1 := v1.(List)
+		l2, ok2 := v2.(List)
+		if !ok1 || !ok2 || len(l1.elements) != len(l2.elements) {
+			return false, nil
+		}
+
+		for i := range l1.elements {
+			eq, err := equal(l1.elements[i], l2.elements[i], precision)
+			if err != nil || !eq {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+
+	if isComplex(v1, v2) {
+		c1, err := toComplex(v1, precision)
+		if err != nil {
+			return false, err
+		}
+		c2, err := toComplex(v2, precision)
+		if err != nil {
+			return false, err
+		}
+		return c1.equal(c2), nil
+	}
+
+	c, err := compare(v1, v2, precision)
+	if err != nil {
+		return false, err
+	}
+	return c == 0, nil
+}
+
+func power(v1, v2 decimal.Decimal, precision int32) (decimal.Decimal, error) {
+	switch {
+	case v1.IsZero() && v2.IsZero():
+		return decimal.Zero, fmt.Errorf("undefined value (0 ^ 0)")
+	case v1.IsZero() && v2.IsNegative():
+		return decimal.Zero, fmt.Errorf("infinity")
+	case v1.IsNegative() && !v2.IsInteger():
+		return decimal.Zero, fmt.Errorf("imaginary value")
+	case v2.IsInteger():
+		return v1.PowWithPrecision(v2, precision)
+	}
+
+	// x^y = e^(y * ln(x)), digits before the point of the result need more precise logarithm
+	estimate, err := naturalLog(v1, 4)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	exponent := estimate.Mul(v2)
+	if exponent.GreaterThan(maxExpArgument) {
+		return decimal.Zero, fmt.Errorf("value is too large")
+	}
+
+	digits := int32(max(exponent.IntPart()/2, 0)) + integerDigits(v2)
+	value, err := naturalLog(v1, seriesPrecision(precision)+digits)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return exponential(value.Mul(v2), precision)
+}
+
+// convertTo converts value into the unit of target, target must be a unit, for example: `km/h`
+func convertTo(v, target Value, precision int32) (Value, error) {
+	quantity, ok := target.(Quantity)
+	if !ok || !quantity.value.Equal(decimal.NewFromInt(1)) {
+		return nil, fmt.Errorf("expected unit, but got `%s`", target)
+	}
+
+	if isComplex(v) {
+		return nil, fmt.Errorf("complex numbers can't have units")
+	}
+
+	value, err := convert(v, quantity.unit, precision)
+	if err != nil {
+		return nil, err
+	}
+	return Quantity{value: value, unit: quantity.unit}, nil
+}
+
+// percent returns value divided by 100, for example: `15%` is `0.15`
+func percent(s *scope, args []Value) (Value, error) {
+	return div(args[0], newInteger(big.NewInt(100), s.exact), s.precision)
+}
+
+// percentChange returns change from the first value to the second one in percents, `(v2 - v1) / v1 * 100`
+func percentChange(s *scope, args []Value) (Value, error) {
+	change, err := sub(args[1], args[0], s.precision)
+	if err != nil {
+		return nil, err
+	}
+	ratio, err := div(change, args[0], s.precision)
+	if err != nil {
+		return nil, err
+	}
+	return mul(ratio, newInteger(big.NewInt(100), s.exact), s.precision)
+}
@@ -1,10 +1,17 @@
+//go:generate go run ./tools/readme
+
 package main
 
 import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"strings"
+	"unicode/utf8"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/spf13/cobra"
@@ -18,6 +25,38 @@ import (
 const (
 	verboseFlag   = "verbose"
 	precisionFlag = "precision"
+	baseFlag      = "base"
+	intModeFlag   = "int"
+	exactFlag     = "exact"
+	complexFlag   = "complex"
+	angleFlag     = "angle"
+	strictFlag    = "strict"
+	linesFlag     = "lines"
+	onErrorFlag   = "on-error"
+	delimiterFlag = "delimiter"
+	outputFlag    = "output"
+	notationFlag  = "notation"
+	digitsFlag    = "digits"
+	groupFlag     = "group"
+	decimalFlag   = "decimal"
+	zerosFlag     = "zeros"
+)
+
+// outputFormat is format of results of expression in arguments and scripts
+type outputFormat string
+
+const (
+	formatText outputFormat = "text" // Result or error as is
+	formatJSON outputFormat = "json" // JSON object for each statement with its result and error location
+)
+
+// errorPolicy is what lines mode does with lines that can't be evaluated, errors are always printed to stderr
+type errorPolicy string
+
+const (
+	policySkip  errorPolicy = "skip"  // Print nothing for the line
+	policyFail  errorPolicy = "fail"  // Stop at the first error
+	policyEmpty errorPolicy = "empty" // Print empty line, so results stay aligned with input lines
 )
 
 func main() {
@@ -28,32 +67,113 @@ func main() {
 		Args:          cobra.ArbitraryArgs,
 		ValidArgs:     []string{"expression\tExpression to evaluate"},
 		Run: func(cmd *cobra.Command, args []string) {
-			verbose, err := cmd.PersistentFlags().GetBool(verboseFlag)
-			utils.Assert(err == nil, verboseFlag, "flag not found")
+			exec, precision, debug := newExecutor(cmd)
 
-			precision, err := cmd.PersistentFlags().GetInt32(precisionFlag)
-			utils.Assert(err == nil, precisionFlag, "flag not found")
+			lines, err := cmd.Flags().GetBool(linesFlag)
+			utils.Assert(err == nil, linesFlag, "flag not found")
 
-			debug := &debugger.Debugger{}
-			debug.SetEnabled(verbose)
+			output := parseOutput(cmd)
 
 			fi, err := os.Stdin.Stat()
 			isPiped := err == nil && (fi.Mode()&os.ModeNamedPipe) != 0
 
+			if output != formatText && (lines || !isPiped && len(args) == 0) {
+				_, _ = fmt.Fprintf(os.Stderr, "Error: %s output is not supported in lines mode and REPL\n", output)
+				os.Exit(1)
+			}
+
+			if lines {
+				policyText, err := cmd.Flags().GetString(onErrorFlag)
+				utils.Assert(err == nil, onErrorFlag, "flag not found")
+
+				delimiterText, err := cmd.Flags().GetString(delimiterFlag)
+				utils.Assert(err == nil, delimiterFlag, "flag not found")
+
+				policy, err := parseErrorPolicy(policyText)
+				if err != nil {
+					_, _ = fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+					os.Exit(1)
+				}
+
+				delimiter, err := parseDelimiter(delimiterText)
+				if err != nil {
+					_, _ = fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+					os.Exit(1)
+				}
+
+				err = runLines(exec, strings.Join(args, " "), delimiter, policy, precision, os.Stdin, os.Stdout, os.Stderr)
+				if err != nil {
+					_, _ = fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+					os.Exit(1)
+				}
+				return
+			}
+
 			if isPiped {
-				expr, readErr := io.ReadAll(os.Stdin)
+				script, readErr := io.ReadAll(os.Stdin)
 				utils.Assert(readErr == nil, "reading from stdin:", readErr)
-				runImmediate(string(expr), precision, debug)
+				runScript(exec, "<stdin>", string(script), precision, debug, output)
 			} else if len(args) != 0 {
-				runImmediate(strings.Join(args, " "), precision, debug)
+				runImmediate(exec, strings.Join(args, " "), precision, debug, output)
 			} else {
-				runRepl(precision, debug)
+				runRepl(exec, precision, debug)
 			}
 		},
 	}
 
 	_ = rootCmd.PersistentFlags().BoolP(verboseFlag, "v", false, "Verbose output")
-	_ = rootCmd.PersistentFlags().Int32P(precisionFlag, "p", 16, "Precision")
+	_ = rootCmd.PersistentFlags().Int32P(precisionFlag, "p", 16, "Precision, number of digits after the point")
+	_ = rootCmd.PersistentFlags().IntP(baseFlag, "b", 10, "Base of results (2, 8, 10 or 16)")
+	_ = rootCmd.PersistentFlags().StringP(intModeFlag, "i", "off",
+		"Integer mode, fixed width integers with wraparound (i8, u8, i16, u16, i32, u32, i64, u64 or off)")
+	_ = rootCmd.PersistentFlags().StringP(exactFlag, "x", "off",
+		"Exact mode, rational numbers printed as fractions (on), mixed numbers (mixed) or off")
+	_ = rootCmd.PersistentFlags().BoolP(complexFlag, "c", false,
+		"Complex mode, square roots and fractional powers of negative numbers are complex")
+	_ = rootCmd.PersistentFlags().StringP(angleFlag, "a", "rad",
+		"Angle mode, unit of angles in trigonometric functions: radians (rad), degrees (deg) or gradians (grad)")
+	_ = rootCmd.PersistentFlags().BoolP(strictFlag, "s", false,
+		"Strict mode, implicit multiplication like 2Pi or 3(4 + 1) is not allowed")
+	_ = rootCmd.PersistentFlags().StringP(notationFlag, "n", "fixed",
+		"Notation of results, plain numbers (fixed), scientific (sci) or engineering with exponents multiple of 3 (eng)")
+	_ = rootCmd.PersistentFlags().Int32P(digitsFlag, "g", 0,
+		"Significant digits of results, if set, results are rounded to them instead of precision")
+	_ = rootCmd.PersistentFlags().String(groupFlag, "",
+		"Separator of thousands in results, character or its name (comma, dot, space, underscore or apostrophe)")
+	_ = rootCmd.PersistentFlags().String(decimalFlag, "",
+		"Decimal separator of results, character or its name (dot or comma)")
+	_ = rootCmd.PersistentFlags().BoolP(zerosFlag, "z", false,
+		"Trailing zeros, fractions of results are padded with zeros up to precision or significant digits")
+
+	_ = rootCmd.Flags().BoolP(linesFlag, "l", false,
+		"Lines mode, each line of stdin is evaluated as it arrives, "+
+			"expression is a template evaluated for each line with its columns $1, $2, ...")
+	_ = rootCmd.Flags().String(onErrorFlag, string(policyFail),
+		"Error policy of lines mode, for lines with errors print nothing (skip), stop (fail) or print empty line (empty)")
+	_ = rootCmd.Flags().StringP(delimiterFlag, "d", ",",
+		"Column delimiter of lines mode, for example: , for CSV or \\t for TSV")
+
+	_ = rootCmd.PersistentFlags().StringP(outputFlag, "o", string(formatText),
+		"Output format of results, as is (text) or JSON object for each statement with result and error location (json)")
+
+	runCmd := &cobra.Command{
+		Use:   "run [flags] file",
+		Short: "Run script file, result of each expression in it is printed on a separate line",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			exec, precision, debug := newExecutor(cmd)
+			output := parseOutput(cmd)
+
+			script, err := os.ReadFile(args[0])
+			if err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+				os.Exit(1)
+			}
+
+			runScript(exec, args[0], string(script), precision, debug, output)
+		},
+	}
+	rootCmd.AddCommand(runCmd)
 
 	utils.WalkCmd(rootCmd, utils.UpdateHelpFlag)
 	if err := rootCmd.Execute(); err != nil {
@@ -62,24 +182,355 @@ func main() {
 	}
 }
 
-func runImmediate(expr string, precision int32, debugger *debugger.Debugger) {
-	exec := executor.NewExecutor(debugger)
+// runImmediate evaluates statements of expression in arguments the same way as scripts
+func runImmediate(
+	exec *executor.Executor, expr string, precision int32, debugger *debugger.Debugger, output outputFormat,
+) {
+	if err := runStatements(exec, expr, precision, debugger, output, os.Stdout); err != nil {
+		if output == formatText {
+			_, _ = fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		}
+		os.Exit(1)
+	}
+}
+
+// jsonResult is result of expression in JSON output
+type jsonResult struct {
+	Expression string           `json:"expression"`
+	Line       int              `json:"line"`   // Line of the statement, lines start from one
+	Column     int              `json:"column"` // Column of the statement in its line, columns start from one
+	Result     string           `json:"result"`
+	Error      *jsonError       `json:"error"`
+	Debug      []debugger.Entry `json:"debug,omitempty"` // Tokens and AST, included in verbose mode
+}
+
+// jsonError is error in JSON output, offsets are in bytes from the start of expression, errors without location
+// cover the whole expression
+type jsonError struct {
+	Message string `json:"message"`
+	Start   int    `json:"start"`
+	End     int    `json:"end"`
+}
+
+// newJSONResult creates JSON result of expression that starts at the offset of the text error locations are in
+func newJSONResult(expr, result string, err error, offset int) jsonResult {
+	output := jsonResult{
+		Expression: expr,
+		Result:     result,
+	}
+
+	var exprErr *executor.ExprError
+	if errors.As(err, &exprErr) && exprErr.Loc.Start >= offset && exprErr.Loc.End <= offset+len(expr) {
+		output.Error = &jsonError{
+			Message: exprErr.Message, Start: exprErr.Loc.Start - offset, End: exprErr.Loc.End - offset,
+		}
+	} else if errors.As(err, &exprErr) {
+		output.Error = &jsonError{Message: exprErr.Message, Start: 0, End: len(expr)}
+	} else if err != nil {
+		output.Error = &jsonError{Message: err.Error(), Start: 0, End: len(expr)}
+	}
+	return output
+}
+
+func writeJSON(w io.Writer, output jsonResult) {
+	data, err := json.Marshal(output)
+	utils.Assert(err == nil, "encoding JSON:", err)
+	_, _ = fmt.Fprintln(w, string(data))
+}
+
+// newExecutor creates executor configured by flags, invalid flag values are reported and terminate the program
+func newExecutor(cmd *cobra.Command) (*executor.Executor, int32, *debugger.Debugger) {
+	verbose, err := cmd.Flags().GetBool(verboseFlag)
+	utils.Assert(err == nil, verboseFlag, "flag not found")
+
+	precision, err := cmd.Flags().GetInt32(precisionFlag)
+	utils.Assert(err == nil, precisionFlag, "flag not found")
+
+	base, err := cmd.Flags().GetInt(baseFlag)
+	utils.Assert(err == nil, baseFlag, "flag not found")
+
+	intModeText, err := cmd.Flags().GetString(intModeFlag)
+	utils.Assert(err == nil, intModeFlag, "flag not found")
+
+	exactText, err := cmd.Flags().GetString(exactFlag)
+	utils.Assert(err == nil, exactFlag, "flag not found")
+
+	complexMode, err := cmd.Flags().GetBool(complexFlag)
+	utils.Assert(err == nil, complexFlag, "flag not found")
+
+	angleText, err := cmd.Flags().GetString(angleFlag)
+	utils.Assert(err == nil, angleFlag, "flag not found")
+
+	strictMode, err := cmd.Flags().GetBool(strictFlag)
+	utils.Assert(err == nil, strictFlag, "flag not found")
+
+	debug := &debugger.Debugger{}
+	debug.SetEnabled(verbose)
+
+	if precision > executor.MaxPrecision {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: precision must not be greater than %d\n", executor.MaxPrecision)
+		os.Exit(1)
+	}
+
+	exec := executor.NewExecutor(debug, nil)
+	if err = exec.SetBase(base); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+
+	intMode, err := executor.ParseIntMode(intModeText)
+	if err == nil {
+		err = exec.SetIntMode(intMode)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+
+	exactMode, err := executor.ParseExactMode(exactText)
+	if err == nil {
+		err = exec.SetExactMode(exactMode)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+
+	exec.SetComplexMode(complexMode)
+
+	angleMode, err := executor.ParseAngleMode(angleText)
+	if err == nil {
+		err = exec.SetAngleMode(angleMode)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+
+	exec.SetStrictMode(strictMode)
+
+	if err = exec.SetFormat(parseFormat(cmd)); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+
+	return exec, precision, debug
+}
+
+func runScript(
+	exec *executor.Executor, name, script string, precision int32, debugger *debugger.Debugger, output outputFormat,
+) {
+	err := runStatements(exec, script, precision, debugger, output, os.Stdout)
+	if err == nil {
+		return
+	}
+
+	if output == formatText {
+		var exprErr *executor.ExprError
+		if errors.As(err, &exprErr) {
+			_, _ = fmt.Fprintf(os.Stderr, "%s:%d:%d: %s\n",
+				name, exprErr.Loc.Line+1, exprErr.Loc.StartColumn+1, exprErr.Message)
+		} else {
+			_, _ = fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		}
+	}
+	os.Exit(1)
+}
+
+// runStatements writes results of statements of the script in the output format, evaluation stops at the first
+// error, it is returned after results of statements before it are written, in JSON format it is written too
+func runStatements(
+	exec *executor.Executor, script string, precision int32, debugger *debugger.Debugger, format outputFormat,
+	output io.Writer,
+) error {
+	statements := exec.ExecuteStatements(script, precision)
+
+	var err error
+	for i, statement := range statements {
+		err = statement.Err
+
+		if format == formatJSON {
+			result := newJSONResult(statement.Text, statement.Result, statement.Err, statement.Loc.Start)
+			result.Line, result.Column = statement.Loc.Line+1, statement.Loc.StartColumn+1
+
+			// Debugger keeps tokens and AST of the last statement only
+			if debugger.Enabled() && i == len(statements)-1 {
+				result.Debug = debugger.Entries()
+			}
+
+			writeJSON(output, result)
+		} else if statement.Result != "" {
+			_, _ = fmt.Fprintln(output, statement.Result)
+		}
+	}
+
+	if err == nil && format == formatText && debugger.Enabled() {
+		_, _ = fmt.Fprintln(output, debugger)
+	}
+	return err
+}
+
+// runLines evaluates each line of input as it arrives, if template is not empty, it is evaluated for each line
+// instead of the line itself, errors of lines that don't stop evaluation are written to errOutput
+func runLines(
+	exec *executor.Executor, template string, delimiter rune, policy errorPolicy, precision int32,
+	input io.Reader, output, errOutput io.Writer,
+) error {
+	// Template is compiled once, so its errors are reported before any line is read
+	var program *executor.Program
+	if template != "" {
+		var err error
+		if program, err = exec.Compile(template); err != nil {
+			return err
+		}
+	}
+
+	// Reader is used instead of scanner, so long lines are not errors that stop evaluation
+	reader := bufio.NewReader(input)
+	for number := 1; ; number++ {
+		line, readErr := reader.ReadString('\n')
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			return fmt.Errorf("reading input: %w", readErr)
+		}
+		if line == "" && readErr != nil {
+			return nil
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+
+		result, err := evaluateLine(exec, program, line, delimiter, precision)
+		if err != nil {
+			if policy == policyFail {
+				return fmt.Errorf("line %d: %w", number, err)
+			}
+			_, _ = fmt.Fprintf(errOutput, "Error: line %d: %s\n", number, err)
+		}
+
+		if err == nil || policy == policyEmpty {
+			_, _ = fmt.Fprintln(output, result)
+		}
+		if readErr != nil {
+			return nil
+		}
+	}
+}
+
+// evaluateLine evaluates line or compiled template with columns of the line
+func evaluateLine(
+	exec *executor.Executor, template *executor.Program, line string, delimiter rune, precision int32,
+) (string, error) {
+	if template == nil {
+		return exec.Execute(line, precision)
+	}
+
+	reader := csv.NewReader(strings.NewReader(line))
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	columns, err := reader.Read()
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
 
-	result, err := exec.Execute(expr, precision)
+	exec.Env().SetColumns(columns)
+	value, err := template.RunWithPrecision(nil, precision)
+	if err != nil {
+		return "", err
+	}
+	return exec.FormatValue(value, precision, exec.Format()), nil
+}
+
+// parseFormat returns format of results set by flags, invalid flag values are reported and terminate the program
+func parseFormat(cmd *cobra.Command) executor.Format {
+	notationText, err := cmd.Flags().GetString(notationFlag)
+	utils.Assert(err == nil, notationFlag, "flag not found")
+
+	digits, err := cmd.Flags().GetInt32(digitsFlag)
+	utils.Assert(err == nil, digitsFlag, "flag not found")
+
+	groupText, err := cmd.Flags().GetString(groupFlag)
+	utils.Assert(err == nil, groupFlag, "flag not found")
+
+	decimalText, err := cmd.Flags().GetString(decimalFlag)
+	utils.Assert(err == nil, decimalFlag, "flag not found")
+
+	zeros, err := cmd.Flags().GetBool(zerosFlag)
+	utils.Assert(err == nil, zerosFlag, "flag not found")
+
+	notation, err := executor.ParseNotation(notationText)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+
+	group, err := executor.ParseSeparator(groupText)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+
+	decimalSeparator, err := executor.ParseSeparator(decimalText)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
+
+	return executor.Format{
+		Notation:          notation,
+		SignificantDigits: digits,
+		TrailingZeros:     zeros,
+		GroupSeparator:    group,
+		DecimalSeparator:  decimalSeparator,
+	}
+}
+
+// parseOutput returns output format set by flag, invalid flag value is reported and terminates the program
+func parseOutput(cmd *cobra.Command) outputFormat {
+	outputName, err := cmd.Flags().GetString(outputFlag)
+	utils.Assert(err == nil, outputFlag, "flag not found")
+
+	output, err := parseOutputFormat(outputName)
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "Error: %s\n", err)
 		os.Exit(1)
 	}
+	return output
+}
 
-	if debugger.Enabled() {
-		fmt.Println(debugger)
+func parseOutputFormat(text string) (outputFormat, error) {
+	switch format := outputFormat(text); format {
+	case formatText, formatJSON:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown output format `%s`", text)
 	}
+}
 
-	fmt.Println(result)
+func parseErrorPolicy(text string) (errorPolicy, error) {
+	switch policy := errorPolicy(text); policy {
+	case policySkip, policyFail, policyEmpty:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown error policy `%s`", text)
+	}
+}
+
+// parseDelimiter returns column delimiter, `\t` is accepted instead of tab that is hard to type
+func parseDelimiter(text string) (rune, error) {
+	if text == `\t` {
+		return '\t', nil
+	}
+
+	delimiter, size := utf8.DecodeRuneInString(text)
+	if size == 0 || size != len(text) || delimiter == utf8.RuneError ||
+		delimiter == '"' || delimiter == '\r' || delimiter == '\n' {
+		return 0, fmt.Errorf("invalid delimiter `%s`", text)
+	}
+	return delimiter, nil
 }
 
-func runRepl(precision int32, debugger *debugger.Debugger) {
-	if _, err := tea.NewProgram(repl.NewModel(debugger, precision)).Run(); err != nil {
+func runRepl(exec *executor.Executor, precision int32, debugger *debugger.Debugger) {
+	if _, err := tea.NewProgram(repl.NewModel(exec, debugger, precision)).Run(); err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "FATAL: %s\n", err)
 		os.Exit(1)
 	}
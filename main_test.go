@@ -0,0 +1,92 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mymmrac/mm/debugger"
+	"github.com/mymmrac/mm/executor"
+)
+
+func TestLines(t *testing.T) {
+	input := "2,10\nx,5\r\n3,25"
+	lineErr := "line 2: expression in rage [1, 2]: apply variable `$1`: column 1 is not a number: `x`"
+
+	testcases := map[string]struct {
+		template string
+		input    string
+		policy   errorPolicy
+		output   string
+		errors   string
+		err      string
+	}{
+		"template": {template: "$1 * $2", input: "2,10\n3,25\n", policy: policyFail, output: "20\n75\n"},
+		"skip":     {template: "$1 * $2", input: input, policy: policySkip, output: "20\n75\n", errors: "Error: " + lineErr},
+		"empty": {
+			template: "$1 * $2", input: input, policy: policyEmpty, output: "20\n\n75\n", errors: "Error: " + lineErr,
+		},
+		"fail":       {template: "$1 * $2", input: input, policy: policyFail, output: "20\n", err: lineErr},
+		"expression": {template: "", input: "1 + 1\n\n2 * 3\n", policy: policyFail, output: "2\n\n6\n"},
+		"long_line": {
+			template: "", input: strings.Repeat("1 + ", 17000) + "1\n", policy: policyFail, output: "17001\n",
+		},
+		"template_error": {
+			template: "1 +", input: input, policy: policySkip, err: "expression at [3]: expected value after `+`",
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			var output, errOutput strings.Builder
+			err := runLines(executor.NewExecutor(nil, nil), tc.template, ',', tc.policy, 16,
+				strings.NewReader(tc.input), &output, &errOutput)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.output, output.String())
+			assert.Equal(t, tc.errors, strings.TrimSuffix(errOutput.String(), "\n"))
+		})
+	}
+}
+
+func TestStatements(t *testing.T) {
+	testcases := map[string]struct {
+		script string
+		format outputFormat
+		output string
+		err    string
+	}{
+		"text": {script: "x = 2; x + 1", format: formatText, output: "3\n"},
+		"json": {
+			script: "x = 2; x + 1", format: formatJSON,
+			output: `{"expression":"x = 2","line":1,"column":1,"result":"","error":null}` + "\n" +
+				`{"expression":"x + 1","line":1,"column":8,"result":"3","error":null}` + "\n",
+		},
+		"text_error": {
+			script: "1; x + 1; 2", format: formatText, output: "1\n", err: "expression at [4]: unknown identifier `x`",
+		},
+		"json_error": {
+			script: "1; x + 1; 2", format: formatJSON,
+			output: `{"expression":"1","line":1,"column":1,"result":"1","error":null}` + "\n" +
+				`{"expression":"x + 1","line":1,"column":4,"result":"",` +
+				`"error":{"message":"unknown identifier ` + "`x`" + `","start":0,"end":1}}` + "\n",
+			err: "expression at [4]: unknown identifier `x`",
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			var output strings.Builder
+			err := runStatements(executor.NewExecutor(nil, nil), tc.script, 16, &debugger.Debugger{}, tc.format,
+				&output)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.output, output.String())
+		})
+	}
+}
@@ -0,0 +1,281 @@
+package repl
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	executor2 "github.com/mymmrac/mm/executor"
+)
+
+const commandPrefix = ":"
+
+// errUsage is returned by commands called with invalid arguments
+var errUsage = errors.New("invalid usage")
+
+// command is a REPL command that changes settings of the session, for example: `:exact on`
+type command struct {
+	name  string
+	usage string
+	run   func(m *Model, args []string) (string, error)
+}
+
+var commands = []command{
+	{
+		name:  "exact",
+		usage: ":exact [off|on|mixed]",
+		run: func(m *Model, args []string) (string, error) {
+			if len(args) > 1 {
+				return "", errUsage
+			}
+
+			if len(args) == 1 {
+				mode, err := executor2.ParseExactMode(args[0])
+				if err != nil {
+					return "", err
+				}
+				if err = m.executor.SetExactMode(mode); err != nil {
+					return "", err
+				}
+			}
+			return "exact mode " + m.executor.ExactMode().String(), nil
+		},
+	},
+	{
+		name:  "complex",
+		usage: ":complex [off|on]",
+		run: func(m *Model, args []string) (string, error) {
+			if len(args) > 1 {
+				return "", errUsage
+			}
+
+			if len(args) == 1 {
+				switch args[0] {
+				case "on":
+					m.executor.SetComplexMode(true)
+				case "off":
+					m.executor.SetComplexMode(false)
+				default:
+					return "", errUsage
+				}
+			}
+
+			if m.executor.ComplexMode() {
+				return "complex mode on", nil
+			}
+			return "complex mode off", nil
+		},
+	},
+	{
+		name:  "angle",
+		usage: ":angle [rad|deg|grad]",
+		run: func(m *Model, args []string) (string, error) {
+			if len(args) > 1 {
+				return "", errUsage
+			}
+
+			if len(args) == 1 {
+				mode, err := executor2.ParseAngleMode(args[0])
+				if err != nil {
+					return "", err
+				}
+				if err = m.executor.SetAngleMode(mode); err != nil {
+					return "", err
+				}
+			}
+			return "angle mode " + m.executor.AngleMode().String(), nil
+		},
+	},
+	{
+		name:  "strict",
+		usage: ":strict [off|on]",
+		run: func(m *Model, args []string) (string, error) {
+			if len(args) > 1 {
+				return "", errUsage
+			}
+
+			if len(args) == 1 {
+				switch args[0] {
+				case "on":
+					m.executor.SetStrictMode(true)
+				case "off":
+					m.executor.SetStrictMode(false)
+				default:
+					return "", errUsage
+				}
+			}
+
+			if m.executor.StrictMode() {
+				return "strict mode on", nil
+			}
+			return "strict mode off", nil
+		},
+	},
+	{
+		name:  "notation",
+		usage: ":notation [fixed|sci|eng]",
+		run: func(m *Model, args []string) (string, error) {
+			if len(args) > 1 {
+				return "", errUsage
+			}
+
+			if len(args) == 1 {
+				notation, err := executor2.ParseNotation(args[0])
+				if err != nil {
+					return "", err
+				}
+				if err = m.updateFormat(func(format *executor2.Format) { format.Notation = notation }); err != nil {
+					return "", err
+				}
+			}
+			return "notation " + m.executor.Format().Notation.String(), nil
+		},
+	},
+	{
+		name:  "digits",
+		usage: ":digits [off|<significant digits>]",
+		run: func(m *Model, args []string) (string, error) {
+			if len(args) > 1 {
+				return "", errUsage
+			}
+
+			if len(args) == 1 {
+				digits := int64(0)
+				if args[0] != "off" {
+					var err error
+					digits, err = strconv.ParseInt(args[0], 10, 32)
+					if err != nil || digits <= 0 {
+						return "", errUsage
+					}
+				}
+				if err := m.updateFormat(func(format *executor2.Format) {
+					format.SignificantDigits = int32(digits)
+				}); err != nil {
+					return "", err
+				}
+			}
+
+			if digits := m.executor.Format().SignificantDigits; digits != 0 {
+				return fmt.Sprintf("significant digits %d", digits), nil
+			}
+			return "significant digits off", nil
+		},
+	},
+	{
+		name:  "group",
+		usage: ":group [off|comma|dot|space|underscore|apostrophe|<separator>]",
+		run: func(m *Model, args []string) (string, error) {
+			if len(args) > 1 {
+				return "", errUsage
+			}
+
+			if len(args) == 1 {
+				separator, err := executor2.ParseSeparator(args[0])
+				if err != nil {
+					return "", err
+				}
+				if err = m.updateFormat(func(format *executor2.Format) {
+					format.GroupSeparator = separator
+				}); err != nil {
+					return "", err
+				}
+			}
+
+			if separator := m.executor.Format().GroupSeparator; separator != "" {
+				return "group separator `" + separator + "`", nil
+			}
+			return "group separator off", nil
+		},
+	},
+	{
+		name:  "decimal",
+		usage: ":decimal [dot|comma|<separator>]",
+		run: func(m *Model, args []string) (string, error) {
+			if len(args) > 1 {
+				return "", errUsage
+			}
+
+			if len(args) == 1 {
+				separator, err := executor2.ParseSeparator(args[0])
+				if err != nil {
+					return "", err
+				}
+				if separator == "" {
+					return "", errUsage
+				}
+				if err = m.updateFormat(func(format *executor2.Format) {
+					format.DecimalSeparator = separator
+				}); err != nil {
+					return "", err
+				}
+			}
+
+			if separator := m.executor.Format().DecimalSeparator; separator != "" {
+				return "decimal separator `" + separator + "`", nil
+			}
+			return "decimal separator `.`", nil
+		},
+	},
+	{
+		name:  "zeros",
+		usage: ":zeros [off|on]",
+		run: func(m *Model, args []string) (string, error) {
+			if len(args) > 1 {
+				return "", errUsage
+			}
+
+			if len(args) == 1 {
+				var zeros bool
+				switch args[0] {
+				case "on":
+					zeros = true
+				case "off":
+					zeros = false
+				default:
+					return "", errUsage
+				}
+				if err := m.updateFormat(func(format *executor2.Format) { format.TrailingZeros = zeros }); err != nil {
+					return "", err
+				}
+			}
+
+			if m.executor.Format().TrailingZeros {
+				return "trailing zeros on", nil
+			}
+			return "trailing zeros off", nil
+		},
+	},
+}
+
+// updateFormat changes format of results, results in history are formatted with the new format too
+func (m *Model) updateFormat(update func(format *executor2.Format)) error {
+	format := m.executor.Format()
+	update(&format)
+	return m.executor.SetFormat(format)
+}
+
+func isCommand(input string) bool {
+	return strings.HasPrefix(strings.TrimSpace(input), commandPrefix)
+}
+
+func (m *Model) runCommand(input string) (string, error) {
+	fields := strings.Fields(strings.TrimPrefix(strings.TrimSpace(input), commandPrefix))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("expected command name after `%s`", commandPrefix)
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != fields[0] {
+			continue
+		}
+
+		result, err := cmd.run(m, fields[1:])
+		if errors.Is(err, errUsage) {
+			return "", fmt.Errorf("usage: %s", cmd.usage)
+		}
+		return result, err
+	}
+
+	return "", fmt.Errorf("unknown command `%s%s`", commandPrefix, fields[0])
+}
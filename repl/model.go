@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/charmbracelet/bubbles/cursor"
 	"github.com/charmbracelet/bubbles/key"
@@ -31,6 +32,7 @@ type Model struct {
 	selectedExpr int
 	expressions  []string
 	results      []string
+	values       []executor2.Value // Values of results formatted with the current format, nil for commands
 
 	executor  *executor2.Executor
 	precision int32
@@ -42,7 +44,7 @@ type Model struct {
 	width, height int
 }
 
-func NewModel(debugger *debugger.Debugger, precision int32) *Model {
+func NewModel(executor *executor2.Executor, debugger *debugger.Debugger, precision int32) *Model {
 	input := textinput.New()
 	input.Placeholder = "..."
 	input.Prompt = "> "
@@ -52,7 +54,7 @@ func NewModel(debugger *debugger.Debugger, precision int32) *Model {
 		input:        input,
 		expressions:  make([]string, 0),
 		selectedExpr: historyNone,
-		executor:     executor2.NewExecutor(debugger),
+		executor:     executor,
 		precision:    precision,
 		debugger:     debugger,
 	}
@@ -68,6 +70,7 @@ func (m *Model) Update(rawMsg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := rawMsg.(type) {
 	case tea.KeyMsg:
 		if msg.Type != tea.KeyLeft && msg.Type != tea.KeyRight {
+			m.error = nil
 			m.exprError = nil
 			keyUpdate = true
 		}
@@ -88,13 +91,32 @@ func (m *Model) Update(rawMsg tea.Msg) (tea.Model, tea.Cmd) {
 				break
 			}
 
-			result, err := m.executor.Execute(expr, m.precision)
+			if isCommand(expr) {
+				result, err := m.runCommand(expr)
+				if err != nil {
+					m.error = err
+					m.exprError = nil
+					m.selectedExpr = historyDisabled
+					break
+				}
+
+				m.expressions = append(m.expressions, expr)
+				m.results = append(m.results, result)
+				m.values = append(m.values, nil)
+
+				m.input.SetValue("")
+				m.selectedExpr = historyNone
+				break
+			}
+
+			value, err := m.executor.Evaluate(expr, m.precision)
 			if err != nil {
 				m.error = err
 				m.selectedExpr = historyDisabled
 
 				if errors.As(err, &m.exprError) {
-					m.input.SetCursor(m.exprError.Loc.End)
+					// Cursor position is a number of characters rather than bytes
+					m.input.SetCursor(utf8.RuneCountInString(expr[:m.exprError.Loc.End]))
 				} else {
 					m.exprError = nil
 				}
@@ -103,7 +125,8 @@ func (m *Model) Update(rawMsg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 			m.expressions = append(m.expressions, expr)
-			m.results = append(m.results, result)
+			m.results = append(m.results, "")
+			m.values = append(m.values, value)
 
 			m.input.SetValue("")
 			m.selectedExpr = historyNone
@@ -129,6 +152,10 @@ func (m *Model) Update(rawMsg tea.Msg) (tea.Model, tea.Cmd) {
 		case key.Matches(msg, keys.NextExpr):
 			if key.Matches(msg, keys.UseResult) && len(m.results) != 0 && m.input.Value() == "" {
 				lastResult := m.results[len(m.results)-1]
+				if lastValue := m.values[len(m.values)-1]; lastValue != nil {
+					// Default format, so that result can be used in expression
+					lastResult = m.executor.FormatValue(lastValue, m.precision, executor2.Format{})
+				}
 				m.input.SetValue(lastResult)
 
 				break
@@ -171,8 +198,11 @@ func (m *Model) Update(rawMsg tea.Msg) (tea.Model, tea.Cmd) {
 	var inputCmd tea.Cmd
 	m.input, inputCmd = m.input.Update(rawMsg)
 
-	if keyUpdate {
-		liveResult, err := m.executor.Execute(m.input.Value(), m.precision)
+	if keyUpdate && isCommand(m.input.Value()) {
+		m.liveResult = ""
+		m.liveError = false
+	} else if keyUpdate {
+		liveResult, err := m.executor.Preview(m.input.Value(), m.precision)
 		if err != nil {
 			if errors.As(err, &m.exprError) {
 				m.liveResult = ""
@@ -208,8 +238,16 @@ func (m *Model) View() string {
 	s.WriteString("\n")
 
 	for i, expr := range m.expressions {
+		result := m.results[i]
+		if m.values[i] != nil {
+			result = m.executor.FormatValue(m.values[i], m.precision, m.executor.Format())
+		}
+
 		s.WriteString(utils.Wrap("> "+expr+"\n", m.width))
-		s.WriteString(utils.Wrap("=> "+m.results[i]+"\n\n", m.width))
+		if result != "" {
+			s.WriteString(utils.Wrap("=> "+result+"\n", m.width))
+		}
+		s.WriteString("\n")
 	}
 
 	m.input.Width = m.width - len(m.input.Prompt) - 1
@@ -223,8 +261,8 @@ func (m *Model) View() string {
 
 		s.WriteString(fmt.Sprintf(
 			"\n%s%s\n",
-			strings.Repeat(" ", loc.Start+len(m.input.Prompt)),
-			errorStyle.Render(strings.Repeat("^", loc.Size())),
+			strings.Repeat(" ", loc.StartColumn+len(m.input.Prompt)),
+			errorStyle.Render(strings.Repeat("^", loc.Width())),
 		))
 	} else if m.liveResult != "" {
 		s.WriteString(utils.Wrap(mutedStyle.Render("\n=> "+m.liveResult+"\n"), m.width))
@@ -0,0 +1,49 @@
+// Command readme updates the list of built-in functions in README.md, it is run by `go generate` from the repository
+// root
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/mymmrac/mm/executor"
+)
+
+const (
+	readmeFile = "README.md"
+	beginMark  = "<!-- functions -->\n"
+	endMark    = "<!-- /functions -->\n"
+)
+
+func main() {
+	if err := run(); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	readme, err := os.ReadFile(readmeFile)
+	if err != nil {
+		return err
+	}
+
+	before, rest, ok := bytes.Cut(readme, []byte(beginMark))
+	if !ok {
+		return fmt.Errorf("no %q in %s", beginMark, readmeFile)
+	}
+	_, after, ok := bytes.Cut(rest, []byte(endMark))
+	if !ok {
+		return fmt.Errorf("no %q in %s", endMark, readmeFile)
+	}
+
+	functions := &bytes.Buffer{}
+	for _, fn := range executor.Functions() {
+		_, _ = fmt.Fprintf(functions, "- `%s` %s\n", fn.Signature, fn.Description)
+	}
+
+	readme = slices.Concat(before, []byte(beginMark), functions.Bytes(), []byte(endMark), after)
+	return os.WriteFile(readmeFile, readme, 0o644)
+}
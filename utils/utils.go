@@ -3,6 +3,7 @@ package utils
 import (
 	"fmt"
 	"strings"
+	"unicode"
 
 	"github.com/muesli/reflow/wordwrap"
 	"github.com/muesli/reflow/wrap"
@@ -18,12 +19,8 @@ func Wrap(text string, limit int) string {
 	return wrap.String(wordwrap.String(text, limit), limit)
 }
 
-func IsSpace(c byte) bool {
-	switch c {
-	case ' ', '\n', '\t', '\v', '\f', '\r', 0x85, 0xA0:
-		return true
-	}
-	return false
+func IsSpace(r rune) bool {
+	return unicode.IsSpace(r)
 }
 
 func IsDigit(c byte) bool {
@@ -33,3 +30,44 @@ func IsDigit(c byte) bool {
 func IsInCharset(c byte, charset string) bool {
 	return strings.IndexByte(charset, c) >= 0
 }
+
+func IsLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+func IsWord(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !IsLetter(s[i]) && !IsDigit(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// EditDistance returns optimal string alignment distance between strings, number of inserted, deleted, replaced or
+// swapped adjacent bytes needed to turn one string into another
+func EditDistance(a, b string) int {
+	distances := make([][]int, len(a)+1)
+	for i := range distances {
+		distances[i] = make([]int, len(b)+1)
+		distances[i][0] = i
+	}
+	for j := range distances[0] {
+		distances[0][j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			distances[i][j] = min(distances[i-1][j]+1, distances[i][j-1]+1, distances[i-1][j-1]+cost)
+
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				distances[i][j] = min(distances[i][j], distances[i-2][j-2]+1)
+			}
+		}
+	}
+	return distances[len(a)][len(b)]
+}